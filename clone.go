@@ -0,0 +1,106 @@
+package jval
+
+// Clone copies v deeply: composites get fresh maps and slices, so changing
+// the copy, e.g. by assigning to an ObjectValidator, leaves v alone.
+// Recursion is copied once and keeps pointing at itself. a Lazy is cloned
+// when it's resolved, a RemoteRef resolves anew. leaves and Refs are shared
+func Clone(v Validator) Validator {
+	return clone(v, make(map[Validator]Validator))
+}
+
+// m maps recursive validators to their copies
+func clone(v Validator, m map[Validator]Validator) Validator {
+	switch a := v.(type) {
+	case AndValidator:
+		o := make(AndValidator, len(a))
+		for i, b := range a {
+			o[i] = clone(b, m)
+		}
+		return o
+	case OrValidator:
+		o := make(OrValidator, len(a))
+		for i, b := range a {
+			o[i] = clone(b, m)
+		}
+		return o
+	case BestMatchOrValidator:
+		o := make(BestMatchOrValidator, len(a))
+		for i, b := range a {
+			o[i] = clone(b, m)
+		}
+		return o
+	case CaseValidator:
+		return CaseValidator(cloneMap(a, m))
+	case ObjectValidator:
+		return ObjectValidator(cloneMap(a, m))
+	case NestedMatchesKindValidator:
+		return NestedMatchesKindValidator{a.k, cloneMap(a.d, m)}
+	case DiscriminatorValidator:
+		return DiscriminatorValidator{a.k, cloneMap(a.d, m)}
+	case MapValidator:
+		return MapValidator{clone(a.e, m)}
+	case ArrayValidator:
+		return ArrayValidator{clone(a.e, m)}
+	case MatchCountValidator:
+		return MatchCountValidator{a.n, a.x, clone(a.e, m)}
+	case EnumCountMapValidator:
+		return EnumCountMapValidator{append([]string{}, a.k...), clone(a.e, m)}
+	case RunLengthEncodingValidator:
+		return RunLengthEncodingValidator{clone(a.e, m), a.s}
+	case NullableValidator:
+		return NullableValidator{clone(a.e, m)}
+	case WhenValidator:
+		return WhenValidator{a.p, clone(a.t, m)}
+	case OptionalValidator:
+		return OptionalValidator{clone(a.e, m)}
+	case AnnotatedValidator:
+		o := make(map[string]interface{}, len(a.m))
+		for k, u := range a.m {
+			o[k] = u
+		}
+		return AnnotatedValidator{clone(a.e, m), o}
+	case AtPathValidator:
+		return AtPathValidator{append([]string{}, a.p...), clone(a.e, m)}
+	case PrefixFieldsValidator:
+		return PrefixFieldsValidator{append([]string{}, a.b...), clone(a.e, m)}
+	case RecoverValidator:
+		return RecoverValidator{clone(a.e, m)}
+	case TeeValidator:
+		return TeeValidator{clone(a.e, m), a.h}
+	case TimeBudgetValidator:
+		return TimeBudgetValidator{a.d, clone(a.e, m)}
+	case MaxDepthValidator:
+		return MaxDepthValidator{clone(a.e, m), a.n}
+	case *MemoizedValidator:
+		return MemoizeN(clone(a.e, m), a.n)
+	case *RemoteRefValidator:
+		return RemoteRef(a.i, a.r)
+	case *RecursiveValidator:
+		if r, k := m[a]; k {
+			return r
+		}
+		r := &RecursiveValidator{}
+		m[a] = r
+		r.Define(clone(a.v, m))
+		return r
+	case *LazyValidator:
+		if r, k := m[a]; k {
+			return r
+		}
+		r := &LazyValidator{}
+		m[a] = r
+		r.f = func() Validator {
+			return clone(a.Validator(), map[Validator]Validator{a: r})
+		}
+		return r
+	}
+	return v
+}
+
+func cloneMap(d map[string]Validator, m map[Validator]Validator) map[string]Validator {
+	o := make(map[string]Validator, len(d))
+	for k, b := range d {
+		o[k] = clone(b, m)
+	}
+	return o
+}
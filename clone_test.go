@@ -0,0 +1,72 @@
+package jval
+
+import "testing"
+
+func TestClone(t *testing.T) {
+	cs := []struct {
+		name   string
+		v      Validator
+		mutate func(Validator)
+		value  string
+	}{
+		{"object", Object(map[string]Validator{"a": String()}),
+			func(c Validator) { c.(ObjectValidator)["a"] = Number() }, `{"a":"x"}`},
+		{"nested object", Object(map[string]Validator{"a": Object(map[string]Validator{"b": String()})}),
+			func(c Validator) { c.(ObjectValidator)["a"].(ObjectValidator)["b"] = Number() }, `{"a":{"b":"x"}}`},
+		{"and", And(String(), NotBlank()),
+			func(c Validator) { c.(AndValidator)[1] = Number() }, `"x"`},
+		{"or", Or(String(), Null()),
+			func(c Validator) { c.(OrValidator)[0] = Number() }, `"x"`},
+		{"case", Case(map[string]Validator{"a": String()}),
+			func(c Validator) { c.(CaseValidator)["a"] = Number() }, `{"a":"x"}`},
+		{"inside array", Array(Object(map[string]Validator{"a": String()})),
+			func(c Validator) { c.(ArrayValidator).e.(ObjectValidator)["a"] = Number() }, `[{"a":"x"}]`},
+		{"annotations", Annotate(String(), map[string]interface{}{"title": "x"}),
+			func(c Validator) { c.(AnnotatedValidator).m["title"] = "y" }, `"x"`},
+	}
+	for _, c := range cs {
+		t.Run(c.name, func(t *testing.T) {
+			d := Clone(c.v)
+			if !Equal(c.v, d) {
+				t.Fatal("clone differs from the original")
+			}
+			c.mutate(d)
+			if Equal(c.v, d) {
+				t.Fatal("mutation didn't change the clone")
+			}
+			if e := c.v.Validate(decodeJSON(t, c.value), []string{}); e != nil {
+				t.Fatalf("original changed: %v", e)
+			}
+		})
+	}
+}
+
+func TestCloneRecursion(t *testing.T) {
+	r := Recursion(func(r Validator) Validator {
+		return Object(map[string]Validator{"name": String(), "children": Array(r)})
+	})
+	c := Clone(r).(*RecursiveValidator)
+	if c == r {
+		t.Fatal("recursion not copied")
+	}
+	o := c.v.(ObjectValidator)
+	if o["children"].(ArrayValidator).e != Validator(c) {
+		t.Fatal("clone doesn't point at itself")
+	}
+	o["name"] = Number()
+	x := decodeJSON(t, `{"name":"a","children":[{"name":"b","children":[]}]}`)
+	if e := r.Validate(x, []string{}); e != nil {
+		t.Fatalf("original changed: %v", e)
+	}
+	if e := c.Validate(x, []string{}); !hasLabel(e, "value_must_be_number") {
+		t.Fatalf("clone not changed: %v", e)
+	}
+}
+
+func BenchmarkClone(b *testing.B) {
+	v := Object(map[string]Validator{"a": String(), "b": Array(Or(Number(), Null())), "c": Object(map[string]Validator{"d": Boolean()})})
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		Clone(v)
+	}
+}
@@ -0,0 +1,241 @@
+package jval
+
+import (
+	"context"
+	"sort"
+	"strconv"
+	"sync"
+)
+
+// Coverage validates like the validator it wraps while recording which Or
+// branches, Case branches, Discriminator cases, kinds of NestedMatchesKind
+// and optional Object keys have been matched by a value. branches are named
+// by their position in the schema, e.g. "/pet/case/dog", "/id/or/1" or
+// "/user/optional/nick".
+// branches behind a Lazy are only reported once it has been resolved
+type Coverage struct {
+	v Validator
+	m sync.Mutex
+	c map[string]bool
+}
+
+func NewCoverage(v Validator) *Coverage {
+	c := &Coverage{c: make(map[string]bool)}
+	c.v = c.instrument(v, "", make(map[Validator]Validator))
+	return c
+}
+
+func (c *Coverage) Validate(v interface{}, f []string) *Error {
+	return c.ValidateCtx(context.Background(), v, f)
+}
+
+func (c *Coverage) ValidateCtx(ctx context.Context, v interface{}, f []string) *Error {
+	return ValidateCtx(ctx, c.v, v, f)
+}
+
+func (c *Coverage) Traverse(v interface{}, f func(interface{}, Validator)) {
+	c.v.Traverse(v, f)
+}
+
+func (c *Coverage) TraversePath(v interface{}, p []string, f func(interface{}, []string, Validator)) {
+	TraversePath(c.v, v, p, f)
+}
+
+func (c *Coverage) Walk(f func(Validator)) {
+	c.WalkVisited(f, Visited{})
+}
+
+func (c *Coverage) WalkVisited(f func(Validator), w Visited) {
+	WalkVisited(c.v, f, w)
+}
+
+func (c *Coverage) ConstraintTree() ConstraintNode {
+	return c.ConstraintTreeVisited(Visited{})
+}
+
+func (c *Coverage) ConstraintTreeVisited(w Visited) ConstraintNode {
+	return ConstraintTreeVisited(c.v, w)
+}
+
+// maps every branch to whether it has been matched
+func (c *Coverage) Report() map[string]bool {
+	c.m.Lock()
+	defer c.m.Unlock()
+	r := make(map[string]bool, len(c.c))
+	for k, v := range c.c {
+		r[k] = v
+	}
+	return r
+}
+
+func (c *Coverage) Uncovered() []string {
+	u := make([]string, 0, 8)
+	for k, v := range c.Report() {
+		if !v {
+			u = append(u, k)
+		}
+	}
+	sort.Strings(u)
+	return u
+}
+
+func (c *Coverage) probe(v Validator, p string, m map[Validator]Validator) Validator {
+	c.c[p] = false
+	return coverageProbe{c, p, c.instrument(v, p, m)}
+}
+
+// m maps recursive validators to their instrumented copies so cycles are
+// instrumented only once
+func (c *Coverage) instrument(v Validator, p string, m map[Validator]Validator) Validator {
+	switch a := v.(type) {
+	case OrValidator:
+		o := make(OrValidator, len(a))
+		for i, b := range a {
+			o[i] = c.probe(b, p+"/or/"+strconv.Itoa(i), m)
+		}
+		return o
+	case BestMatchOrValidator:
+		o := make(BestMatchOrValidator, len(a))
+		for i, b := range a {
+			o[i] = c.probe(b, p+"/or/"+strconv.Itoa(i), m)
+		}
+		return o
+	case CaseValidator:
+		o := make(CaseValidator, len(a))
+		for k, b := range a {
+			o[k] = c.probe(b, p+"/case/"+k, m)
+		}
+		return o
+	case NestedMatchesKindValidator:
+		o := make(map[string]Validator, len(a.d))
+		for k, b := range a.d {
+			o[k] = c.probe(b, p+"/kind/"+k, m)
+		}
+		return NestedMatchesKindValidator{a.k, o}
+	case DiscriminatorValidator:
+		o := make(map[string]Validator, len(a.d))
+		for k, b := range a.d {
+			o[k] = c.probe(b, p+"/discriminator/"+k, m)
+		}
+		return DiscriminatorValidator{a.k, o}
+	case AndValidator:
+		o := make(AndValidator, len(a))
+		for i, b := range a {
+			o[i] = c.instrument(b, p, m)
+		}
+		return o
+	case ObjectValidator:
+		o := make(ObjectValidator, len(a))
+		for k, b := range a {
+			if !isOptional(b) {
+				o[k] = c.instrument(b, p+"/"+k, m)
+				continue
+			}
+			q := p + "/optional/" + k
+			c.c[q] = false
+			o[k] = coverageProbe{c, q, c.instrument(b, p+"/"+k, m)}
+		}
+		return o
+	case MapValidator:
+		return MapValidator{c.instrument(a.e, p+"/*", m)}
+	case ArrayValidator:
+		return ArrayValidator{c.instrument(a.e, p+"/*", m)}
+	case MatchCountValidator:
+		return MatchCountValidator{a.n, a.x, c.instrument(a.e, p+"/*", m)}
+	case EnumCountMapValidator:
+		return EnumCountMapValidator{a.k, c.instrument(a.e, p+"/*", m)}
+	case RunLengthEncodingValidator:
+		return RunLengthEncodingValidator{c.instrument(a.e, p+"/*/0", m), a.s}
+	case NullableValidator:
+		return NullableValidator{c.instrument(a.e, p, m)}
+	case WhenValidator:
+		return WhenValidator{a.p, c.instrument(a.t, p, m)}
+	case OptionalValidator:
+		return OptionalValidator{c.instrument(a.e, p, m)}
+	case AnnotatedValidator:
+		return AnnotatedValidator{c.instrument(a.e, p, m), a.m}
+	case AtPathValidator:
+		q := p
+		for _, k := range a.p {
+			q += "/" + k
+		}
+		return AtPathValidator{a.p, c.instrument(a.e, q, m)}
+	case *MemoizedValidator:
+		return MemoizeN(c.instrument(a.e, p, m), a.n)
+	case MaxDepthValidator:
+		return MaxDepthValidator{c.instrument(a.e, p, m), a.n}
+	case PrefixFieldsValidator:
+		return PrefixFieldsValidator{a.b, c.instrument(a.e, p, m)}
+	case RecoverValidator:
+		return RecoverValidator{c.instrument(a.e, p, m)}
+	case TeeValidator:
+		return TeeValidator{c.instrument(a.e, p, m), a.h}
+	case TimeBudgetValidator:
+		return TimeBudgetValidator{a.d, c.instrument(a.e, p, m)}
+	case *RecursiveValidator:
+		if r, k := m[a]; k {
+			return r
+		}
+		r := &RecursiveValidator{}
+		m[a] = r
+		r.Define(c.instrument(a.v, p, m))
+		return r
+	case *LazyValidator:
+		if r, k := m[a]; k {
+			return r
+		}
+		r := &LazyValidator{}
+		m[a] = r
+		r.f = func() Validator {
+			c.m.Lock()
+			defer c.m.Unlock()
+			return c.instrument(a.Validator(), p, m)
+		}
+		return r
+	}
+	return v
+}
+
+type coverageProbe struct {
+	c *Coverage
+	p string
+	e Validator
+}
+
+func (a coverageProbe) Validate(v interface{}, f []string) *Error {
+	return a.ValidateCtx(context.Background(), v, f)
+}
+
+func (a coverageProbe) ValidateCtx(ctx context.Context, v interface{}, f []string) *Error {
+	e := ValidateCtx(ctx, a.e, v, f)
+	if e == nil {
+		a.c.m.Lock()
+		a.c.c[a.p] = true
+		a.c.m.Unlock()
+	}
+	return e
+}
+
+func (a coverageProbe) Traverse(v interface{}, f func(interface{}, Validator)) {
+	a.e.Traverse(v, f)
+}
+
+func (a coverageProbe) TraversePath(v interface{}, p []string, f func(interface{}, []string, Validator)) {
+	TraversePath(a.e, v, p, f)
+}
+
+func (a coverageProbe) Walk(f func(Validator)) {
+	a.WalkVisited(f, Visited{})
+}
+
+func (a coverageProbe) WalkVisited(f func(Validator), w Visited) {
+	WalkVisited(a.e, f, w)
+}
+
+func (a coverageProbe) ConstraintTree() ConstraintNode {
+	return a.ConstraintTreeVisited(Visited{})
+}
+
+func (a coverageProbe) ConstraintTreeVisited(w Visited) ConstraintNode {
+	return ConstraintTreeVisited(a.e, w)
+}
@@ -0,0 +1,69 @@
+package jval
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestCoverage(t *testing.T) {
+	cs := []struct {
+		name      string
+		v         Validator
+		values    []string
+		uncovered []string
+	}{
+		{
+			"uncovered or branch",
+			Object(map[string]Validator{"id": Or(String(), Number(), Boolean())}),
+			[]string{`{"id":"a"}`, `{"id":1}`},
+			[]string{"/id/or/2"},
+		},
+		{
+			"all or branches",
+			Or(String(), Number()),
+			[]string{`"a"`, `1`},
+			[]string{},
+		},
+		{
+			"case branches",
+			Object(map[string]Validator{"pet": Case(map[string]Validator{"dog": Boolean(), "cat": Number()})}),
+			[]string{`{"pet":{"dog":true}}`, `{"pet":{"cat":true}}`},
+			[]string{"/pet/case/cat"},
+		},
+		{
+			"optional keys",
+			Object(map[string]Validator{"user": Object(map[string]Validator{"name": String(), "nick": Optional(String()), "bio": Annotate(Optional(String()), map[string]interface{}{"title": "bio"})})}),
+			[]string{`{"user":{"name":"a","nick":"b"}}`, `{"user":{"name":"a","bio":1}}`},
+			[]string{"/user/optional/bio"},
+		},
+		{
+			"failing values cover nothing",
+			Array(Or(String(), Number())),
+			[]string{`[null]`},
+			[]string{"/*/or/0", "/*/or/1"},
+		},
+	}
+	for _, c := range cs {
+		t.Run(c.name, func(t *testing.T) {
+			v := NewCoverage(c.v)
+			for _, s := range c.values {
+				v.Validate(decodeJSON(t, s), []string{})
+			}
+			if u := v.Uncovered(); !reflect.DeepEqual(u, c.uncovered) {
+				t.Fatalf("uncovered %v, want %v", u, c.uncovered)
+			}
+		})
+	}
+}
+
+func TestCoverageValidatesLikeWrapped(t *testing.T) {
+	v := NewCoverage(Object(map[string]Validator{"id": Or(String(), Number())}))
+	runValidateCases(t, []validateCase{
+		{"valid", v, decodeJSON(t, `{"id":"a"}`), ""},
+		{"invalid", v, decodeJSON(t, `{"id":true}`), "value_must_be_number"},
+	})
+}
+
+func BenchmarkCoverage(b *testing.B) {
+	benchmarkValidate(b, NewCoverage(Object(map[string]Validator{"id": Or(String(), Number())})), decodeJSON(b, `{"id":1}`))
+}
@@ -0,0 +1,354 @@
+package jval
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Describe renders v in plain English, children indented below their
+// parents. a Recursion or Lazy reached again inside itself reads "(recursive)"
+func Describe(v Validator) string {
+	return describe(v, "", make(map[Validator]bool))
+}
+
+func describe(v Validator, i string, s map[Validator]bool) string {
+	switch a := v.(type) {
+	case Lambda, CtxLambda:
+		return "a value accepted by a custom function"
+	case AnythingValidator:
+		return "anything"
+	case StringValidator:
+		return "a string"
+	case NumberValidator:
+		return "a number"
+	case BooleanValidator:
+		return "a boolean"
+	case NullValidator:
+		return "null"
+	case AndValidator:
+		return "all of:" + describeList(a, i, s)
+	case OrValidator:
+		return "one of:" + describeList(a, i, s)
+	case BestMatchOrValidator:
+		return "one of:" + describeList(a, i, s)
+	case NullableValidator:
+		return "null or " + describe(a.e, i, s)
+	case WhenValidator:
+		return "if a custom condition holds, " + describe(a.t, i, s)
+	case OptionalValidator:
+		return "optionally " + describe(a.e, i, s)
+	case AnnotatedValidator:
+		ks := make([]string, 0, len(a.m))
+		for k, _ := range a.m {
+			ks = append(ks, k)
+		}
+		sort.Strings(ks)
+		for j, k := range ks {
+			b, _ := json.Marshal(a.m[k])
+			ks[j] = k + ": " + string(b)
+		}
+		return describe(a.e, i, s) + " (" + strings.Join(ks, ", ") + ")"
+	case *MemoizedValidator:
+		return describe(a.e, i, s)
+	case MaxDepthValidator:
+		return describe(a.e, i, s) + fmt.Sprintf(", nested at most %d deep", a.n)
+	case PrefixFieldsValidator:
+		return describe(a.e, i, s)
+	case RecoverValidator:
+		return describe(a.e, i, s)
+	case TeeValidator:
+		return describe(a.e, i, s)
+	case TimeBudgetValidator:
+		return describe(a.e, i, s)
+	case CaseValidator:
+		return "an object with exactly one of the keys:" + describeKeys(a, i, s)
+	case NestedMatchesKindValidator:
+		return fmt.Sprintf("an object whose %q names which one of these keys it has:", a.k) + describeKeys(a.d, i, s)
+	case DiscriminatorValidator:
+		return fmt.Sprintf("an object whose %q picks which of these it is:", a.k) + describeKeys(a.d, i, s)
+	case ObjectValidator:
+		return "an object with keys:" + describeKeys(a, i, s)
+	case MapValidator:
+		return "an object whose values are each " + describe(a.e, i, s)
+	case EnumCountMapValidator:
+		return fmt.Sprintf("an object with keys among %s whose values are each ", strings.Join(a.k, ", ")) + describe(a.e, i, s)
+	case ArrayValidator:
+		return "an array whose elements are each " + describe(a.e, i, s)
+	case MatchCountValidator:
+		if a.x {
+			return fmt.Sprintf("an array with exactly %d elements that are each ", a.n) + describe(a.e, i, s)
+		}
+		return fmt.Sprintf("an array with at least %d elements that are each ", a.n) + describe(a.e, i, s)
+	case RunLengthEncodingValidator:
+		return "a run-length encoding of values that are each " + describe(a.e, i, s)
+	case AtPathValidator:
+		return fmt.Sprintf("a value whose %s is ", strings.Join(a.p, ".")) + describe(a.e, i, s)
+	case ElementsFromFieldValidator:
+		return fmt.Sprintf("an object whose %q elements all appear in %q", a.k, a.r)
+	case MatchesShapeValidator:
+		return fmt.Sprintf("an object whose %q arrays have the dimensions listed in %q", a.d, a.s)
+	case AtMostNKeysValidator:
+		return fmt.Sprintf("an object with at most %d of the keys %s", a.n, strings.Join(a.k, ", "))
+	case KeyCountBetweenValidator:
+		if a.y == -1 {
+			return fmt.Sprintf("an object with at least %d keys", a.x)
+		}
+		return fmt.Sprintf("an object with between %d and %d keys", a.x, a.y)
+	case OneOfKeysValidator:
+		d := map[string]string{"exactly_one_key_required": "exactly", "at_least_one_key_required": "at least", "at_most_one_key_required": "at most"}[a.l]
+		return fmt.Sprintf("an object with %s one of the keys %s", d, strings.Join(a.k, ", "))
+	case RequiresKeysValidator:
+		return fmt.Sprintf("an object that, if it has %q, also has %s", a.i, strings.Join(a.t, ", "))
+	case SlugIDValidator:
+		return fmt.Sprintf("an object whose %q matches its %q", a.s, a.i)
+	case FieldsEqualValidator:
+		return fmt.Sprintf("a value whose %s equals its %s", strings.Join(a.x, "."), strings.Join(a.y, "."))
+	case MetadataSizeLimitValidator:
+		return fmt.Sprintf("an object of at most %d bytes of keys and values", a.n)
+	case WeightsSumToValidator:
+		return fmt.Sprintf("an array of objects whose %q add up to %g", a.k, a.s)
+	case FixedIntervalValidator:
+		return fmt.Sprintf("an array of timestamps %g seconds apart", a.i)
+	case StringSetValidator:
+		return fmt.Sprintf("an array of at least %d distinct non-empty strings", a.n)
+	case HomogeneousValidator:
+		if a.o {
+			return "an object whose values are all of the same type"
+		}
+		return "an array whose elements are all of the same type"
+	case NoNullItemsValidator:
+		return "an array without nulls"
+	case SortedValidator:
+		if a.s {
+			return "a strictly sorted array"
+		}
+		return "a sorted array"
+	case RegexValidator:
+		return fmt.Sprintf("a string matching /%s/", a.x)
+	case LengthBetweenValidator:
+		if a.x == a.y {
+			return fmt.Sprintf("a string or array of length %d", a.x)
+		}
+		return fmt.Sprintf("a string or array of length between %d and %d", a.x, a.y)
+	case LengthMinValidator:
+		return fmt.Sprintf("a string or array of length at least %d", a.x)
+	case LengthMaxValidator:
+		return fmt.Sprintf("a string or array of length at most %d", a.y)
+	case GraphemeLengthBetweenValidator:
+		return fmt.Sprintf("a string of between %d and %d characters as displayed", a.x, a.y)
+	case MaxByteLengthValidator:
+		return fmt.Sprintf("a string of at most %d bytes", a.n)
+	case MinEntropyBitsValidator:
+		return fmt.Sprintf("a string with at least %g bits of entropy", a.b)
+	case NotBlankValidator:
+		return "a string that isn't blank"
+	case TrimmedValidator:
+		if a.c == "" {
+			return "a string without leading or trailing whitespace"
+		}
+		return fmt.Sprintf("a string not starting or ending with any of %q", a.c)
+	case EncodableInValidator:
+		return "a string encodable in " + a.c
+	case URLValidator:
+		if len(a.s) == 0 {
+			return "a URL"
+		}
+		return "a URL with scheme " + strings.Join(a.s, " or ")
+	case IPValidator:
+		if a.v == 0 {
+			return "an IP address"
+		}
+		return fmt.Sprintf("an IPv%d address", a.v)
+	case Base64Validator:
+		d := "a base64"
+		if a.u {
+			d += "url"
+		}
+		if a.r {
+			return d + " string without padding"
+		}
+		return d + " string"
+	case DurationValidator:
+		return "an ISO 8601 duration"
+	case TimeFormatValidator:
+		return fmt.Sprintf("a time in the Go layout %q", a.l)
+	case LuhnValidator:
+		return "a number passing the Luhn check"
+	case SemVerValidator:
+		return "a semantic version"
+	case HexValidator:
+		return "a hex string"
+	case HexColorValidator:
+		if a.a {
+			return "a hex color like #fff, #ffffff or #ffffffff"
+		}
+		return "a hex color like #fff or #ffffff"
+	case CharsetValidator:
+		if a.p {
+			return "a string without control characters"
+		}
+		return "an ASCII string"
+	case CleanStringValidator:
+		if a.w {
+			return "a string without a byte order mark or control characters other than tabs and line breaks"
+		}
+		return "a string without a byte order mark or control characters"
+	case HostnameValidator:
+		return "a host name"
+	case CIDRValidator:
+		return "a CIDR block"
+	case FragmentValidator:
+		d := map[string]string{"prefix": "starting with", "suffix": "ending with", "substring": "containing"}[a.w]
+		if a.i {
+			return fmt.Sprintf("a string %s %q, ignoring case", d, a.s)
+		}
+		return fmt.Sprintf("a string %s %q", d, a.s)
+	case InternalRefValidator:
+		return "a JSON pointer into the document"
+	case LatitudeValidator:
+		return "a latitude"
+	case LongitudeValidator:
+		return "a longitude"
+	case GeoPointValidator:
+		return "a [longitude, latitude] pair or an object with lat and lon"
+	case CurrencyAmountValidator:
+		return "a non-negative amount with at most two decimal places"
+	case NumberBetweenValidator:
+		return fmt.Sprintf("a number between %g and %g", a.x, a.y)
+	case ProportionValidator:
+		if a.y == 1 {
+			return "a fraction between 0 and 1"
+		}
+		return "a percentage between 0 and 100"
+	case NumberMinValidator:
+		return fmt.Sprintf("a number of at least %g", a.x)
+	case NumberMaxValidator:
+		return fmt.Sprintf("a number of at most %g", a.y)
+	case FiniteNumberValidator:
+		return "a finite number"
+	case SignValidator:
+		return "a " + strings.Replace(a.s, "_", "-", -1) + " number"
+	case MultipleOfValidator:
+		return fmt.Sprintf("a multiple of %g", a.n)
+	case WholeNumberValidator:
+		return "a whole number"
+	case WholeNumberBetweenValidator:
+		return fmt.Sprintf("a whole number between %d and %d", a.x, a.y)
+	case IntBetweenValidator:
+		return fmt.Sprintf("an integer between %d and %d", a.x, a.y)
+	case EnumIntValidator:
+		cs := make([]int, 0, len(a.c))
+		for c, _ := range a.c {
+			cs = append(cs, c)
+		}
+		sort.Ints(cs)
+		d := make([]string, len(cs))
+		for j, c := range cs {
+			d[j] = fmt.Sprintf("%d (%s)", c, a.c[c])
+		}
+		return "one of " + strings.Join(d, ", ")
+	case IntWidthValidator:
+		if a.u {
+			return fmt.Sprintf("an unsigned %d-bit integer", a.b)
+		}
+		return fmt.Sprintf("a %d-bit integer", a.b)
+	case Int64BetweenValidator:
+		return fmt.Sprintf("a 64-bit integer between %d and %d", a.x, a.y)
+	case FileModeValidator:
+		if a.s {
+			return fmt.Sprintf("an octal file mode string within %#o", a.m)
+		}
+		return fmt.Sprintf("a file mode within %#o", a.m)
+	case PortValidator:
+		return fmt.Sprintf("a port number between %d and %d", a.x, a.y)
+	case ExactlyValidator:
+		b, _ := json.Marshal(a.j)
+		return "exactly " + string(b)
+	case OneOfValidator:
+		d := make([]string, len(a.o))
+		for j, o := range a.o {
+			b, _ := json.Marshal(o)
+			d[j] = string(b)
+		}
+		return "one of " + strings.Join(d, ", ")
+	case SubsetOfValidator:
+		d := make([]string, len(a.o))
+		for j, o := range a.o {
+			b, _ := json.Marshal(o)
+			d[j] = string(b)
+		}
+		return "an array of values among " + strings.Join(d, ", ")
+	case OneOfFoldValidator:
+		return fmt.Sprintf("one of %s in any case", strings.Join(a.o, ", "))
+	case ExactlyNumberValidator:
+		return fmt.Sprintf("%g, give or take %g", a.n, a.e)
+	case ExactlyFoldValidator:
+		return fmt.Sprintf("%q in any case", a.s)
+	case *RecursiveValidator:
+		if s[a] {
+			return "(recursive)"
+		}
+		s[a] = true
+		defer delete(s, a)
+		return describe(a.v, i, s)
+	case *LazyValidator:
+		if s[a] {
+			return "(recursive)"
+		}
+		s[a] = true
+		defer delete(s, a)
+		return describe(a.Validator(), i, s)
+	case *RemoteRefValidator:
+		return fmt.Sprintf("the remote schema %q", a.i)
+	case RefValidator:
+		return fmt.Sprintf("the schema %q", a.n)
+	case *Coverage:
+		return describe(a.v, i, s)
+	case coverageProbe:
+		return describe(a.e, i, s)
+	}
+	return fmt.Sprintf("a value accepted by %T", v)
+}
+
+func describeList(vs []Validator, i string, s map[Validator]bool) string {
+	d := ""
+	for _, v := range vs {
+		d += "\n" + i + "  " + describe(v, i+"  ", s)
+	}
+	return d
+}
+
+func describeKeys(m map[string]Validator, i string, s map[Validator]bool) string {
+	ks := make([]string, 0, len(m))
+	for k, _ := range m {
+		ks = append(ks, k)
+	}
+	sort.Strings(ks)
+	d := ""
+	for _, k := range ks {
+		d += "\n" + i + "  " + k + ": " + describe(m[k], i+"  ", s)
+	}
+	return d
+}
+
+// PrintConstraintTree renders v's constraint tree, one node per line with
+// children indented below their parent and prefixed by their key. the
+// markers for recursion and references are printed as they are
+func PrintConstraintTree(v Validator) string {
+	return printConstraintNode(v.ConstraintTree(), "")
+}
+
+func printConstraintNode(n ConstraintNode, i string) string {
+	d := fmt.Sprint(n.Constraint)
+	ks := make([]string, 0, len(n.Children))
+	for k, _ := range n.Children {
+		ks = append(ks, k)
+	}
+	sort.Strings(ks)
+	for _, k := range ks {
+		d += "\n" + i + "  " + k + ": " + printConstraintNode(n.Children[k], i+"  ")
+	}
+	return d
+}
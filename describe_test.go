@@ -0,0 +1,77 @@
+package jval
+
+import "testing"
+
+func TestDescribe(t *testing.T) {
+	cs := []struct {
+		name string
+		v    Validator
+		want string
+	}{
+		{"leaf", String(), "a string"},
+		{"object", Object(map[string]Validator{"name": String(), "age": WholeNumberBetween(0, 150), "nick": Optional(String())}),
+			"an object with keys:\n  age: a whole number between 0 and 150\n  name: a string\n  nick: optionally a string"},
+		{"nested indentation", Or(String(), And(Number(), NumberBetween(0, 1))),
+			"one of:\n  a string\n  all of:\n    a number\n    a number between 0 and 1"},
+		{"recursion", Recursion(func(r Validator) Validator { return Or(Null(), Array(r)) }),
+			"one of:\n  null\n  an array whose elements are each (recursive)"},
+		{"nullable", Nullable(Array(Boolean())), "null or an array whose elements are each a boolean"},
+	}
+	for _, c := range cs {
+		t.Run(c.name, func(t *testing.T) {
+			if got := Describe(c.v); got != c.want {
+				t.Fatalf("got %q, want %q", got, c.want)
+			}
+		})
+	}
+}
+
+func TestPrintConstraintTree(t *testing.T) {
+	cs := []struct {
+		name string
+		v    Validator
+		want string
+	}{
+		{"leaf", String(), `typeof(v)==="string"`},
+		{"nested object", Object(map[string]Validator{
+			"name": String(),
+			"address": Object(map[string]Validator{
+				"city": String(),
+				"zip":  Number(),
+			}),
+			"tags": Array(Boolean()),
+		}), `typeof(v)==="object" && v.keys()===[<keys>]
+  address: typeof(v)==="object" && v.keys()===[<keys>]
+    city: typeof(v)==="string"
+    zip: typeof(v)==="number"
+  name: typeof(v)==="string"
+  tags: typeof(v)==="array"
+    *: typeof(v)==="boolean"`},
+		{"recursion marker", Recursion(func(r Validator) Validator { return Array(r) }), `typeof(v)==="array"
+  *: <recursion>`},
+		{"ref marker", NewRegistry().Ref("user"), `<ref:user>`},
+	}
+	for _, c := range cs {
+		t.Run(c.name, func(t *testing.T) {
+			if got := PrintConstraintTree(c.v); got != c.want {
+				t.Fatalf("got\n%s\nwant\n%s", got, c.want)
+			}
+		})
+	}
+}
+
+func BenchmarkPrintConstraintTree(b *testing.B) {
+	v := Object(map[string]Validator{"name": String(), "tags": Array(String()), "address": Object(map[string]Validator{"city": String()})})
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		PrintConstraintTree(v)
+	}
+}
+
+func BenchmarkDescribe(b *testing.B) {
+	v := Object(map[string]Validator{"name": String(), "tags": Array(Or(String(), Number())), "age": WholeNumberBetween(0, 150)})
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		Describe(v)
+	}
+}
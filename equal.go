@@ -0,0 +1,177 @@
+package jval
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"math"
+	"reflect"
+	"sort"
+	"strconv"
+	"unsafe"
+)
+
+// validators with mutable state or self-references; only the same instance
+// is equal to them
+var identityTypes = map[reflect.Type]bool{
+	reflect.TypeOf(&RecursiveValidator{}): true,
+	reflect.TypeOf(&LazyValidator{}):      true,
+	reflect.TypeOf(&RemoteRefValidator{}): true,
+	reflect.TypeOf(&Coverage{}):           true,
+	reflect.TypeOf(&Registry{}):           true,
+	reflect.TypeOf(&MemoizedValidator{}):  true,
+}
+
+// Equal reports whether a and b are of the same type with the same
+// parameters and equal children. Recursion, Lazy, RemoteRef and Memoize are
+// compared by identity, Refs by name and registry. funcs are equal only when
+// they're copies of the same func value, so closures made by the same func
+// literal but capturing different values aren't
+func Equal(a, b Validator) bool {
+	x, y := structure{i: true}, structure{i: true}
+	x.value(reflect.ValueOf(a))
+	y.value(reflect.ValueOf(b))
+	return bytes.Equal(x.b, y.b)
+}
+
+// Fingerprint returns a SHA-256 hex digest of the same walk Equal compares,
+// so structurally equal validators share a fingerprint across processes. a
+// Recursion is hashed as its definition, with the Recursion itself written
+// as how far up it was entered. anything else Equal compares by identity
+// can't be named outside the process, so a tree holding a Lambda, Lazy,
+// RemoteRef, Ref, Memoize or other func has no fingerprint and Fingerprint
+// returns false
+func Fingerprint(v Validator) (string, bool) {
+	s := structure{}
+	s.value(reflect.ValueOf(v))
+	if s.u {
+		return "", false
+	}
+	h := sha256.Sum256(s.b)
+	return hex.EncodeToString(h[:]), true
+}
+
+// the canonical encoding of a validator behind Equal and Fingerprint. i
+// writes identity types and funcs as their address, as Equal wants them;
+// without it they make the encoding unstable, u, and Recursions are walked
+// into, r holding the ones being walked. reflect only hands out a func's code
+// pointer, which closures of the same literal share, so in identity mode
+// values are made addressable to read the func value itself
+type structure struct {
+	b []byte
+	i bool
+	u bool
+	r []uintptr
+}
+
+func (s *structure) token(t string) {
+	s.b = strconv.AppendInt(s.b, int64(len(t)), 10)
+	s.b = append(s.b, ':')
+	s.b = append(s.b, t...)
+}
+
+func (s *structure) address(p uintptr) {
+	s.u = true
+	s.token(strconv.FormatUint(uint64(p), 16))
+}
+
+// r, or an addressable copy of it, without the read-only flag of values
+// reached through unexported fields. copies keep func values as they are
+func addressable(r reflect.Value) reflect.Value {
+	if !r.CanAddr() {
+		c := reflect.New(r.Type()).Elem()
+		c.Set(r)
+		r = c
+	}
+	return reflect.NewAt(r.Type(), unsafe.Pointer(r.UnsafeAddr())).Elem()
+}
+
+func (s *structure) value(r reflect.Value) {
+	if !r.IsValid() {
+		s.token("")
+		return
+	}
+	if s.i {
+		r = addressable(r)
+	}
+	s.token(r.Type().String())
+	switch r.Kind() {
+	case reflect.Interface:
+		if r.IsNil() {
+			s.token("nil")
+			return
+		}
+		s.value(r.Elem())
+	case reflect.Ptr:
+		if r.IsNil() {
+			s.token("nil")
+			return
+		}
+		if !identityTypes[r.Type()] {
+			s.value(r.Elem())
+			return
+		}
+		if s.i || r.Type() != reflect.TypeOf(&RecursiveValidator{}) {
+			s.address(r.Pointer())
+			return
+		}
+		for i, p := range s.r {
+			if p == r.Pointer() {
+				s.token("recursion " + strconv.Itoa(len(s.r)-i))
+				return
+			}
+		}
+		s.r = append(s.r, r.Pointer())
+		s.value(r.Elem().FieldByName("v"))
+		s.r = s.r[:len(s.r)-1]
+	case reflect.Func, reflect.Chan, reflect.UnsafePointer:
+		if r.IsNil() {
+			s.token("nil")
+			return
+		}
+		if s.i && r.Kind() == reflect.Func {
+			s.address(*(*uintptr)(unsafe.Pointer(r.UnsafeAddr())))
+			return
+		}
+		s.address(r.Pointer())
+	case reflect.Map:
+		ks := make([]string, 0, r.Len())
+		es := make(map[string]reflect.Value, r.Len())
+		for _, k := range r.MapKeys() {
+			t := structure{i: s.i}
+			t.value(k)
+			s.u = s.u || t.u
+			ks = append(ks, string(t.b))
+			es[string(t.b)] = r.MapIndex(k)
+		}
+		sort.Strings(ks)
+		s.token(strconv.Itoa(len(ks)))
+		for _, k := range ks {
+			s.b = append(s.b, k...)
+			s.value(es[k])
+		}
+	case reflect.Slice, reflect.Array:
+		s.token(strconv.Itoa(r.Len()))
+		for i, l := 0, r.Len(); i < l; i++ {
+			s.value(r.Index(i))
+		}
+	case reflect.Struct:
+		for i, l := 0, r.NumField(); i < l; i++ {
+			s.value(r.Field(i))
+		}
+	case reflect.Bool:
+		s.token(strconv.FormatBool(r.Bool()))
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		s.token(strconv.FormatInt(r.Int(), 10))
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		s.token(strconv.FormatUint(r.Uint(), 10))
+	case reflect.Float32, reflect.Float64:
+		s.token(strconv.FormatUint(math.Float64bits(r.Float()), 16))
+	case reflect.Complex64, reflect.Complex128:
+		c := r.Complex()
+		s.token(strconv.FormatUint(math.Float64bits(real(c)), 16))
+		s.token(strconv.FormatUint(math.Float64bits(imag(c)), 16))
+	case reflect.String:
+		s.token(r.String())
+	}
+}
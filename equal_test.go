@@ -0,0 +1,156 @@
+package jval
+
+import "testing"
+
+func isString(v interface{}, f []string) *Error {
+	return String().Validate(v, f)
+}
+
+func gt(n float64) func(interface{}) bool {
+	return func(v interface{}) bool {
+		x, k := v.(float64)
+		return k && x > n
+	}
+}
+
+func shorterThan(n int) Validator {
+	return Lambda(func(v interface{}, f []string) *Error {
+		if s, k := v.(string); k && len(s) >= n {
+			return &Error{"string_too_long", f, n}
+		}
+		return NoError
+	})
+}
+
+func TestEqual(t *testing.T) {
+	one, short := gt(1), shorterThan(3)
+	r := Recursion(func(r Validator) Validator { return Or(Null(), Array(r)) })
+	l := Lazy(func() Validator { return String() })
+	cs := []struct {
+		name string
+		a, b Validator
+		want bool
+	}{
+		{"same leaf", String(), String(), true},
+		{"different leaves", String(), Number(), false},
+		{"same bounds", NumberBetween(0, 10), NumberBetween(0, 10), true},
+		{"different bounds", NumberBetween(0, 10), NumberBetween(0, 11), false},
+		{"same object", Object(map[string]Validator{"a": String(), "b": Optional(Number())}), Object(map[string]Validator{"b": Optional(Number()), "a": String()}), true},
+		{"object child differs", Object(map[string]Validator{"a": String()}), Object(map[string]Validator{"a": Number()}), false},
+		{"object keys differ", Object(map[string]Validator{"a": String()}), Object(map[string]Validator{"b": String()}), false},
+		{"object extra key", Object(map[string]Validator{"a": String()}), Object(map[string]Validator{"a": String(), "b": String()}), false},
+		{"or order matters", Or(String(), Number()), Or(Number(), String()), false},
+		{"nested arrays", Array(Array(Boolean())), Array(Array(Boolean())), true},
+		{"array and map", Array(String()), Map(String()), false},
+		{"exactly", Exactly("a"), Exactly("a"), true},
+		{"exactly different", Exactly("a"), Exactly("b"), false},
+		{"recursion itself", r, r, true},
+		{"separate recursions", r, Recursion(func(r Validator) Validator { return Or(Null(), Array(r)) }), false},
+		{"lazy itself", l, l, true},
+		{"separate lazies", l, Lazy(func() Validator { return String() }), false},
+		{"lambda itself", Lambda(isString), Lambda(isString), true},
+		{"in object with lambda", Object(map[string]Validator{"a": Lambda(isString)}), Object(map[string]Validator{"a": Lambda(isString)}), true},
+		{"different lambdas", Lambda(isString), Lambda(func(v interface{}, f []string) *Error { return NoError }), false},
+		{"closures capturing different values", When(gt(1), String()), When(gt(100), String()), false},
+		{"separate closures capturing the same value", When(gt(1), String()), When(gt(1), String()), false},
+		{"same closure", When(one, String()), When(one, String()), true},
+		{"same closure lambda", Object(map[string]Validator{"a": short}), Object(map[string]Validator{"a": short}), true},
+		{"lambdas capturing different values", shorterThan(3), shorterThan(30), false},
+		{"nil", nil, nil, true},
+		{"nil and leaf", nil, String(), false},
+	}
+	for _, c := range cs {
+		t.Run(c.name, func(t *testing.T) {
+			if got := Equal(c.a, c.b); got != c.want {
+				t.Fatalf("Equal = %t, want %t", got, c.want)
+			}
+			if got := Equal(c.b, c.a); got != c.want {
+				t.Fatalf("Equal isn't symmetric")
+			}
+		})
+	}
+}
+
+func TestEqualReflexive(t *testing.T) {
+	x := And(String(), Lambda(func(v interface{}, f []string) *Error { return NoError }))
+	if !Equal(x, x) {
+		t.Fatal("a validator holding a Lambda must equal itself")
+	}
+}
+
+func BenchmarkEqual(b *testing.B) {
+	x := Object(map[string]Validator{"a": String(), "b": Array(NumberBetween(0, 10)), "c": Or(Null(), Boolean())})
+	y := Object(map[string]Validator{"a": String(), "b": Array(NumberBetween(0, 10)), "c": Or(Null(), Boolean())})
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		Equal(x, y)
+	}
+}
+
+func TestFingerprint(t *testing.T) {
+	tree := func(r Validator) Validator {
+		return Object(map[string]Validator{"name": String(), "children": Array(r)})
+	}
+	cs := []struct {
+		name string
+		a, b Validator
+		same bool
+	}{
+		{"equal trees", Object(map[string]Validator{"a": NumberBetween(0, 10), "b": Optional(String())}),
+			Object(map[string]Validator{"b": Optional(String()), "a": NumberBetween(0, 10)}), true},
+		{"changed bound", NumberBetween(0, 10), NumberBetween(0, 11), false},
+		{"changed key", Object(map[string]Validator{"a": String()}), Object(map[string]Validator{"b": String()}), false},
+		{"changed type", Array(String()), Map(String()), false},
+		{"or order", Or(String(), Number()), Or(Number(), String()), false},
+		{"separate recursions", Recursion(tree), Recursion(tree), true},
+		{"recursion body differs", Recursion(tree),
+			Recursion(func(r Validator) Validator {
+				return Object(map[string]Validator{"name": Number(), "children": Array(r)})
+			}), false},
+		{"nested recursions", Recursion(func(r Validator) Validator { return Array(Recursion(tree)) }),
+			Recursion(func(r Validator) Validator { return Array(Recursion(tree)) }), true},
+		{"nil", nil, nil, true},
+	}
+	for _, c := range cs {
+		t.Run(c.name, func(t *testing.T) {
+			x, k := Fingerprint(c.a)
+			y, l := Fingerprint(c.b)
+			if !k || !l || len(x) != 64 || len(y) != 64 {
+				t.Fatalf("fingerprints %q and %q", x, y)
+			}
+			if (x == y) != c.same {
+				t.Fatalf("same = %t, want %t", x == y, c.same)
+			}
+		})
+	}
+}
+
+func TestFingerprintUnstable(t *testing.T) {
+	r := NewRegistry()
+	cs := []struct {
+		name string
+		v    Validator
+	}{
+		{"lambda", Lambda(isString)},
+		{"lambda inside", Object(map[string]Validator{"a": Array(Lambda(isString))})},
+		{"lazy", Lazy(func() Validator { return String() })},
+		{"ref", r.Ref("a")},
+		{"memoize", Memoize(String())},
+		{"when", Object(map[string]Validator{"a": When(func(interface{}) bool { return true }, String())})},
+	}
+	for _, c := range cs {
+		t.Run(c.name, func(t *testing.T) {
+			if f, k := Fingerprint(c.v); k || f != "" {
+				t.Fatalf("got %q, want none", f)
+			}
+		})
+	}
+}
+
+func BenchmarkFingerprint(b *testing.B) {
+	x := Object(map[string]Validator{"a": String(), "b": Array(NumberBetween(0, 10)), "c": Or(Null(), Boolean())})
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		Fingerprint(x)
+	}
+}
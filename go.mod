@@ -0,0 +1,8 @@
+module github.com/thwd/jval
+
+go 1.26.0
+
+require (
+	github.com/rivo/uniseg v0.4.7
+	golang.org/x/text v0.42.0
+)
@@ -0,0 +1,313 @@
+package jval
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+)
+
+// keywords that carry no constraint and are skipped
+var jsonSchemaAnnotations = map[string]bool{
+	"$schema":     true,
+	"$id":         true,
+	"$comment":    true,
+	"title":       true,
+	"description": true,
+	"default":     true,
+	"examples":    true,
+}
+
+// keywords read together with the one they're mapped to
+var jsonSchemaGroups = map[string]string{
+	"additionalProperties": "properties",
+	"required":             "properties",
+	"minimum":              "maximum",
+	"minLength":            "maxLength",
+}
+
+// FromJSONSchema builds a validator from a draft-07 JSON Schema. only type,
+// properties, required, additionalProperties (true or false), items (a
+// single schema), enum, minimum, maximum, minLength, maxLength, pattern,
+// anyOf and allOf are understood; any other keyword is an error. objects
+// with additionalProperties false become an Object. others stay open, so
+// their properties are checked by AtPath and a missing required one is
+// "path_not_found". as in JSON Schema, the object keywords and items pass
+// values that aren't objects or arrays unless type says otherwise
+func FromJSONSchema(data []byte) (Validator, error) {
+	var s interface{}
+	if err := json.Unmarshal(data, &s); err != nil {
+		return nil, err
+	}
+	return fromJSONSchema(s, "#")
+}
+
+func fromJSONSchema(s interface{}, p string) (Validator, error) {
+	if b, k := s.(bool); k && b {
+		return Anything(), nil
+	}
+	m, k := s.(map[string]interface{})
+	if !k {
+		return nil, fmt.Errorf("jsonschema: %s: schema must be an object or true", p)
+	}
+	ks := make([]string, 0, len(m))
+	for k, _ := range m {
+		ks = append(ks, k)
+	}
+	sort.Strings(ks)
+	vs := make([]Validator, 0, 4)
+	if t, x := m["type"]; x {
+		v, err := jsonSchemaType(t, m, p)
+		if err != nil {
+			return nil, err
+		}
+		if v != nil {
+			vs = append(vs, v)
+		}
+	}
+	d := make(map[string]bool, len(ks))
+	for _, k := range ks {
+		if g, x := jsonSchemaGroups[k]; x {
+			k = g
+		}
+		if d[k] {
+			continue
+		}
+		d[k] = true
+		var v Validator
+		var err error
+		switch k {
+		case "type":
+		case "properties":
+			if v, err = jsonSchemaObject(m, p); err == nil {
+				v = jsonSchemaIf(m, "object", v)
+			}
+		case "items":
+			if _, x := m[k].([]interface{}); x {
+				return nil, fmt.Errorf("jsonschema: %s/items: tuple items are not supported", p)
+			}
+			if v, err = fromJSONSchema(m[k], p+"/items"); err == nil {
+				v = jsonSchemaIf(m, "array", Array(v))
+			}
+		case "enum":
+			o, x := m[k].([]interface{})
+			if !x || len(o) == 0 {
+				return nil, fmt.Errorf("jsonschema: %s/enum: must be a non-empty array", p)
+			}
+			v = OneOf(o...)
+		case "maximum":
+			v, err = jsonSchemaRange(m, p)
+		case "maxLength":
+			v, err = jsonSchemaLength(m, p)
+		case "pattern":
+			x, c := m[k].(string)
+			if !c {
+				return nil, fmt.Errorf("jsonschema: %s/pattern: must be a string", p)
+			}
+			if _, err := regexp.Compile(x); err != nil {
+				return nil, fmt.Errorf("jsonschema: %s/pattern: %s", p, err)
+			}
+			v = Regex(x, "value_not_matched_pattern", false, false)
+		case "anyOf", "allOf":
+			var ss []Validator
+			if ss, err = jsonSchemaList(m[k], p+"/"+k); err == nil {
+				if k == "anyOf" {
+					v = Or(ss...)
+				} else {
+					v = And(ss...)
+				}
+			}
+		default:
+			if !jsonSchemaAnnotations[k] {
+				return nil, fmt.Errorf("jsonschema: %s: unsupported keyword %q", p, k)
+			}
+		}
+		if err != nil {
+			return nil, err
+		}
+		if v != nil {
+			vs = append(vs, v)
+		}
+	}
+	if len(vs) == 0 {
+		return Anything(), nil
+	}
+	return And(vs...), nil
+}
+
+// nil when properties or items already check the type
+func jsonSchemaType(t interface{}, m map[string]interface{}, p string) (Validator, error) {
+	ts, k := t.([]interface{})
+	if !k {
+		ts = []interface{}{t}
+	}
+	vs := make([]Validator, 0, len(ts))
+	for _, t := range ts {
+		n, _ := t.(string)
+		switch n {
+		case "string":
+			vs = append(vs, String())
+		case "number":
+			vs = append(vs, Number())
+		case "integer":
+			vs = append(vs, WholeNumber())
+		case "boolean":
+			vs = append(vs, Boolean())
+		case "null":
+			vs = append(vs, Null())
+		case "object":
+			_, x := m["properties"]
+			_, y := m["required"]
+			_, z := m["additionalProperties"]
+			if len(ts) == 1 && (x || y || z) {
+				return nil, nil
+			}
+			vs = append(vs, Map(Anything()))
+		case "array":
+			if _, x := m["items"]; x && len(ts) == 1 {
+				return nil, nil
+			}
+			vs = append(vs, Array(Anything()))
+		default:
+			return nil, fmt.Errorf("jsonschema: %s/type: unknown type %v", p, t)
+		}
+	}
+	if len(vs) == 0 {
+		return nil, fmt.Errorf("jsonschema: %s/type: no types given", p)
+	}
+	return Or(vs...), nil
+}
+
+// properties and items only apply to objects and arrays, any other value
+// passes them. v is left alone when type already demands t
+func jsonSchemaIf(m map[string]interface{}, t string, v Validator) Validator {
+	if m["type"] == t {
+		return v
+	}
+	return When(func(v interface{}) bool {
+		switch v.(type) {
+		case map[string]interface{}:
+			return t == "object"
+		case []interface{}:
+			return t == "array"
+		}
+		return false
+	}, v)
+}
+
+func jsonSchemaObject(m map[string]interface{}, p string) (Validator, error) {
+	a, x := m["additionalProperties"]
+	if x && a != false && a != true {
+		return nil, fmt.Errorf("jsonschema: %s: additionalProperties other than a boolean is not supported", p)
+	}
+	ps, k := m["properties"].(map[string]interface{})
+	if _, x := m["properties"]; x && !k {
+		return nil, fmt.Errorf("jsonschema: %s/properties: must be an object", p)
+	}
+	rs, k := m["required"].([]interface{})
+	if _, x := m["required"]; x && !k {
+		return nil, fmt.Errorf("jsonschema: %s/required: must be an array", p)
+	}
+	r := make(map[string]bool, len(rs))
+	for _, k := range rs {
+		n, x := k.(string)
+		if !x {
+			return nil, fmt.Errorf("jsonschema: %s/required: must only hold strings", p)
+		}
+		r[n] = true
+	}
+	d := make(map[string]Validator, len(ps))
+	for k, s := range ps {
+		v, err := fromJSONSchema(s, p+"/properties/"+k)
+		if err != nil {
+			return nil, err
+		}
+		if !r[k] {
+			v = Optional(v)
+		}
+		d[k] = v
+	}
+	for k, _ := range r {
+		if _, x := d[k]; !x {
+			d[k] = Anything()
+		}
+	}
+	if a == false {
+		return Object(d), nil
+	}
+	ks := make([]string, 0, len(d))
+	for k, _ := range d {
+		ks = append(ks, k)
+	}
+	sort.Strings(ks)
+	vs := make([]Validator, 1, len(d)+1)
+	vs[0] = Map(Anything())
+	for _, k := range ks {
+		vs = append(vs, AtPath([]string{k}, d[k]))
+	}
+	return And(vs...), nil
+}
+
+func jsonSchemaRange(m map[string]interface{}, p string) (Validator, error) {
+	x, y := math.Inf(-1), math.Inf(1)
+	if n, k := m["minimum"]; k {
+		if x, k = n.(float64); !k {
+			return nil, fmt.Errorf("jsonschema: %s/minimum: must be a number", p)
+		}
+	}
+	if n, k := m["maximum"]; k {
+		if y, k = n.(float64); !k {
+			return nil, fmt.Errorf("jsonschema: %s/maximum: must be a number", p)
+		}
+	}
+	switch {
+	case y < x:
+		return nil, fmt.Errorf("jsonschema: %s: maximum is less than minimum", p)
+	case math.IsInf(y, 1):
+		return NumberMin(x), nil
+	case math.IsInf(x, -1):
+		return NumberMax(y), nil
+	}
+	return NumberBetween(x, y), nil
+}
+
+func jsonSchemaLength(m map[string]interface{}, p string) (Validator, error) {
+	x, y := -1, -1
+	for k, n := range map[string]*int{"minLength": &x, "maxLength": &y} {
+		u, c := m[k]
+		if !c {
+			continue
+		}
+		l, c := u.(float64)
+		if !c || l < 0 || l != math.Trunc(l) {
+			return nil, fmt.Errorf("jsonschema: %s/%s: must be a non-negative integer", p, k)
+		}
+		*n = int(l)
+	}
+	switch {
+	case y == -1:
+		return LengthMin(x), nil
+	case x == -1:
+		return LengthMax(y), nil
+	case y < x:
+		return nil, fmt.Errorf("jsonschema: %s: maxLength is less than minLength", p)
+	}
+	return LengthBetween(x, y), nil
+}
+
+func jsonSchemaList(s interface{}, p string) ([]Validator, error) {
+	ss, k := s.([]interface{})
+	if !k || len(ss) == 0 {
+		return nil, fmt.Errorf("jsonschema: %s: must be a non-empty array", p)
+	}
+	vs := make([]Validator, len(ss))
+	for i, s := range ss {
+		v, err := fromJSONSchema(s, fmt.Sprintf("%s/%d", p, i))
+		if err != nil {
+			return nil, err
+		}
+		vs[i] = v
+	}
+	return vs, nil
+}
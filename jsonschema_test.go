@@ -0,0 +1,129 @@
+package jval
+
+import "testing"
+
+const jsonSchemaGolden = `{
+	"$schema": "http://json-schema.org/draft-07/schema#",
+	"title": "order",
+	"type": "object",
+	"additionalProperties": false,
+	"required": ["id", "items"],
+	"properties": {
+		"id": {"type": "string", "pattern": "^o-[0-9]+$"},
+		"note": {"type": "string", "maxLength": 20},
+		"status": {"enum": ["open", "paid"]},
+		"items": {
+			"type": "array",
+			"items": {
+				"type": "object",
+				"required": ["sku", "qty"],
+				"properties": {
+					"sku": {"type": "string", "minLength": 3, "maxLength": 8},
+					"qty": {"type": "integer", "minimum": 1, "maximum": 99},
+					"price": {"anyOf": [{"type": "number", "minimum": 0}, {"type": "null"}]}
+				}
+			}
+		}
+	}
+}`
+
+// the tree FromJSONSchema builds for jsonSchemaGolden, as described
+const jsonSchemaGoldenDescription = `an object with keys:
+  id: all of:
+    a string
+    a string matching /^o-[0-9]+$/
+  items: an array whose elements are each all of:
+    an object whose values are each anything
+    a value whose price is optionally one of:
+      all of:
+        a number
+        a number of at least 0
+      null
+    a value whose qty is all of:
+      a whole number
+      a number between 1 and 99
+    a value whose sku is all of:
+      a string
+      a string or array of length between 3 and 8
+  note: optionally all of:
+    a string
+    a string or array of length at most 20
+  status: optionally one of "open", "paid"`
+
+func TestFromJSONSchemaGolden(t *testing.T) {
+	v, err := FromJSONSchema([]byte(jsonSchemaGolden))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d := Describe(v); d != jsonSchemaGoldenDescription {
+		t.Fatalf("got\n%s\nwant\n%s", d, jsonSchemaGoldenDescription)
+	}
+	runValidateCases(t, []validateCase{
+		{"minimal", v, decodeJSON(t, `{"id":"o-1","items":[]}`), ""},
+		{"full", v, decodeJSON(t, `{"id":"o-1","note":"x","status":"paid","items":[{"sku":"abc","qty":2,"price":null,"extra":true}]}`), ""},
+		{"closed top level", v, decodeJSON(t, `{"id":"o-1","items":[],"b":1}`), "unexpected_object_key"},
+		{"missing required", v, decodeJSON(t, `{"items":[]}`), "missing_object_key"},
+		{"pattern", v, decodeJSON(t, `{"id":"x-1","items":[]}`), "value_not_matched_pattern"},
+		{"enum", v, decodeJSON(t, `{"id":"o-1","items":[],"status":"lost"}`), "value_not_one_of"},
+		{"nested missing required", v, decodeJSON(t, `{"id":"o-1","items":[{"sku":"abc"}]}`), "path_not_found"},
+		{"nested range", v, decodeJSON(t, `{"id":"o-1","items":[{"sku":"abc","qty":100}]}`), "value_must_have_value_between"},
+		{"nested integer", v, decodeJSON(t, `{"id":"o-1","items":[{"sku":"abc","qty":1.5}]}`), "value_must_be_whole_number"},
+		{"nested length", v, decodeJSON(t, `{"id":"o-1","items":[{"sku":"ab","qty":1}]}`), "value_must_have_length_between"},
+	})
+}
+
+func TestFromJSONSchemaOpenObjects(t *testing.T) {
+	cs := []struct {
+		name   string
+		schema string
+		value  string
+		label  string
+	}{
+		{"open accepts other keys", `{"properties":{"a":{"type":"string"}}}`, `{"b":1}`, ""},
+		{"open checks present properties", `{"properties":{"a":{"type":"string"}}}`, `{"a":1}`, "value_must_be_string"},
+		{"open requires", `{"required":["a"]}`, `{"b":1}`, "path_not_found"},
+		{"open with true", `{"properties":{"a":{}},"additionalProperties":true}`, `{"b":1}`, ""},
+		{"properties pass non-objects", `{"properties":{"a":{}}}`, `[]`, ""},
+		{"required passes non-objects", `{"required":["a"]}`, `"x"`, ""},
+		{"typed object rejects non-objects", `{"type":"object","properties":{"a":{}}}`, `[]`, "value_must_be_object"},
+		{"items pass non-arrays", `{"items":{"type":"string"}}`, `{"a":1}`, ""},
+		{"items check arrays", `{"items":{"type":"string"}}`, `["a",1]`, "value_must_be_string"},
+		{"typed array rejects non-arrays", `{"type":"array","items":{}}`, `"x"`, "value_must_be_array"},
+		{"items with several types", `{"type":["array","null"],"items":{"type":"string"}}`, `null`, ""},
+		{"closed", `{"properties":{"a":{}},"additionalProperties":false}`, `{"b":1}`, "unexpected_object_key"},
+	}
+	for _, c := range cs {
+		t.Run(c.name, func(t *testing.T) {
+			v, err := FromJSONSchema([]byte(c.schema))
+			if err != nil {
+				t.Fatal(err)
+			}
+			runValidateCases(t, []validateCase{{c.name, v, decodeJSON(t, c.value), c.label}})
+		})
+	}
+}
+
+func TestFromJSONSchemaErrors(t *testing.T) {
+	for _, s := range []string{
+		`{"type":"object","additionalProperties":{"type":"string"}}`,
+		`{"items":[{}]}`,
+		`{"enum":[]}`,
+		`{"minimum":2,"maximum":1}`,
+		`{"pattern":"("}`,
+		`{"oneOf":[{}]}`,
+		`{"type":"date"}`,
+		`[]`,
+	} {
+		if _, err := FromJSONSchema([]byte(s)); err == nil {
+			t.Errorf("%s: no error", s)
+		}
+	}
+}
+
+func BenchmarkFromJSONSchema(b *testing.B) {
+	v, err := FromJSONSchema([]byte(jsonSchemaGolden))
+	if err != nil {
+		b.Fatal(err)
+	}
+	benchmarkValidate(b, v, decodeJSON(b, `{"id":"o-1","note":"x","items":[{"sku":"abc","qty":2},{"sku":"defg","qty":3,"price":9.5}]}`))
+}
@@ -1,11 +1,29 @@
 package jval
 
 import (
+	"container/list"
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
 	"math"
+	"net"
+	"net/url"
+	"reflect"
 	"regexp"
+	"sort"
 	"strconv"
 	"strings"
+	"sync"
+	"time"
+	"unicode"
 	"unicode/utf8"
+
+	"github.com/rivo/uniseg"
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/ianaindex"
 )
 
 type Error struct {
@@ -34,14 +52,317 @@ func (e *Error) Equals(o *Error) bool {
 	return true // can't compare contexts, TODO: maybe make it of type Equaler
 }
 
+// the children of "and" and "or" errors. their Context holds them as a
+// plain []*Error
+type Errors []*Error
+
+// the nested errors of an "and" or "or" error, or the element's error of an
+// "array_item" one. an "object_keys_mismatch" error only sums up the key
+// errors next to it, so it has no children but isn't a leaf either: Leaves,
+// Summary, FlatMap and Localize skip it. nil for any other error
+func (e *Error) Children() Errors {
+	if e == nil {
+		return nil
+	}
+	if e.Label == "object_keys_mismatch" {
+		return Errors{}
+	}
+	switch c := e.Context.(type) {
+	case []*Error:
+		if e.Label == "and" || e.Label == "or" {
+			return Errors(c)
+		}
+	case *Error:
+		if e.Label == "array_item" {
+			return Errors{c}
+		}
+	}
+	return nil
+}
+
+// the array indices leading to each failing array element, outermost first
+func (e *Error) FailingIndices() [][]int {
+	return failingIndices(e, nil, nil)
+}
+
+func failingIndices(e *Error, p []int, r [][]int) [][]int {
+	if e == nil {
+		return r
+	}
+	if e.Label == "array_item" {
+		i, _ := strconv.Atoi(e.Field[len(e.Field)-1])
+		p = append(p[:len(p):len(p)], i)
+	}
+	cs := e.Children()
+	if cs == nil && len(p) > 0 && (len(r) == 0 || !reflect.DeepEqual(r[len(r)-1], p)) {
+		return append(r, p)
+	}
+	for _, c := range cs {
+		r = failingIndices(c, p, r)
+	}
+	return r
+}
+
+// flattens "and", "or" and "array_item" errors into the non-aggregate errors
+// they contain. leaf errors already carry their full field path
+func (e *Error) Leaves() []*Error {
+	if e == nil {
+		return nil
+	}
+	if cs := e.Children(); cs != nil {
+		ls := make([]*Error, 0, len(cs))
+		for _, c := range cs {
+			ls = append(ls, c.Leaves()...)
+		}
+		return ls
+	}
+	return []*Error{e}
+}
+
+type IndexedLeaf struct {
+	Path []string
+	Err  *Error
+}
+
+// pairs every leaf error with the path of the value it's about: its field,
+// array indices included, extended by the key for errors about a single
+// missing or unexpected object key
+func IndexedLeaves(e *Error) []IndexedLeaf {
+	ls := e.Leaves()
+	r := make([]IndexedLeaf, len(ls))
+	for i, l := range ls {
+		p := appendField(l.Field)
+		if k, x := l.Context.(string); x && (l.Label == "missing_object_key" || l.Label == "unexpected_object_key") {
+			p = append(p, k)
+		}
+		r[i] = IndexedLeaf{p, l}
+	}
+	return r
+}
+
+type ErrorSummary struct {
+	Total   int            `json:"total"`
+	ByLabel map[string]int `json:"by_label"`
+}
+
+// counts the leaf errors, overall and per label
+func (e *Error) Summary() ErrorSummary {
+	s := ErrorSummary{0, make(map[string]int)}
+	for _, l := range e.Leaves() {
+		s.Total++
+		s.ByLabel[l.Label]++
+	}
+	return s
+}
+
+// maps dotted field paths to leaf labels. if several leaves share a path,
+// the first one wins
+func (e *Error) FlatMap() map[string]string {
+	m := make(map[string]string)
+	for _, l := range e.Leaves() {
+		k := strings.Join(l.Field, ".")
+		if _, x := m[k]; !x {
+			m[k] = l.Label
+		}
+	}
+	return m
+}
+
+// turns a leaf error into a message for the user, e.g. interpolating the
+// bounds found in its context
+type Translator func(label string, field []string, context interface{}) string
+
+// maps dotted field paths to translated leaf errors. the alternatives of an
+// "or" failing at the same path are joined with " or "; otherwise the first
+// message for a path wins, as in FlatMap
+func Localize(e *Error, t Translator) map[string]string {
+	m := make(map[string]string)
+	localize(e, t, m)
+	return m
+}
+
+func localize(e *Error, t Translator, m map[string]string) {
+	if e == nil {
+		return
+	}
+	cs := e.Children()
+	if cs == nil {
+		k := strings.Join(e.Field, ".")
+		if _, x := m[k]; !x {
+			m[k] = t(e.Label, e.Field, e.Context)
+		}
+		return
+	}
+	if e.Label != "or" {
+		for _, c := range cs {
+			localize(c, t, m)
+		}
+		return
+	}
+	o := make(map[string]string)
+	for _, c := range cs {
+		a := make(map[string]string)
+		localize(c, t, a)
+		for k, s := range a {
+			if u, x := o[k]; x {
+				s = u + " or " + s
+			}
+			o[k] = s
+		}
+	}
+	for k, s := range o {
+		if _, x := m[k]; !x {
+			m[k] = s
+		}
+	}
+}
+
+// like Leaves, but keeps "or" errors since they're genuine disjunctions;
+// their alternatives are flattened in turn into a [][]*Error context
+func (e *Error) Flatten() []*Error {
+	if e == nil {
+		return nil
+	}
+	cs := e.Children()
+	if cs == nil {
+		return []*Error{e}
+	}
+	if e.Label != "or" {
+		fs := make([]*Error, 0, len(cs))
+		for _, c := range cs {
+			fs = append(fs, c.Flatten()...)
+		}
+		return fs
+	}
+	as := make([][]*Error, 0, len(cs))
+	for _, c := range cs {
+		as = append(as, c.Flatten())
+	}
+	return []*Error{{e.Label, e.Field, as}}
+}
+
+// marshals Flatten(); use json.Marshal on the error itself for the raw tree
+func (e *Error) FlatJSON() ([]byte, error) {
+	return json.Marshal(e.Flatten())
+}
+
 var NoError *Error = nil
 
+// when positive, Object, Map and Array stop validating once they've
+// collected this many errors. 1 makes them fail fast. independently of this
+// they stop with "validation_cancelled" once their context is done
+var MaxErrors = 0
+
+func tooManyErrors(ae []*Error) bool {
+	return MaxErrors > 0 && len(ae) >= MaxErrors
+}
+
 type Validator interface {
 	Validate(value interface{}, field []string) *Error
 	Traverse(interface{}, func(interface{}, Validator))
+	// visits every validator in the tree, without needing a value
+	Walk(func(Validator))
 	ConstraintTree() ConstraintNode
 }
 
+// implemented by validators that hand a context down to their children,
+// e.g. to reach a CtxLambda looking things up in a database
+type ContextValidator interface {
+	Validator
+	ValidateCtx(ctx context.Context, value interface{}, field []string) *Error
+}
+
+// validates with ctx if v takes a context, plainly otherwise
+func ValidateCtx(ctx context.Context, v Validator, value interface{}, f []string) *Error {
+	if !ownField(v) {
+		f = appendField(f)
+	}
+	if c, k := v.(ContextValidator); k {
+		return c.ValidateCtx(ctx, value, f)
+	}
+	return v.Validate(value, f)
+}
+
+// implemented by validators containing others, to hand the field path
+// down to them
+type PathTraverser interface {
+	Validator
+	TraversePath(value interface{}, field []string, f func(value interface{}, field []string, validator Validator))
+}
+
+// like Traverse, but f also gets the field path of each value, built as
+// Validate builds it. f may keep the path, it isn't reused
+func TraversePath(v Validator, value interface{}, field []string, f func(value interface{}, field []string, validator Validator)) {
+	if t, k := v.(PathTraverser); k {
+		t.TraversePath(value, field, f)
+		return
+	}
+	f(value, field, v)
+}
+
+// the Lazy and Recursion validators a Walk or ConstraintTree call has
+// entered. each call makes its own, so concurrent calls don't see each
+// other's
+type Visited map[Validator]bool
+
+// implemented by validators containing others, to hand Visited down to
+// them. a cycle through a validator that doesn't implement it isn't noticed
+type VisitWalker interface {
+	Validator
+	WalkVisited(f func(Validator), w Visited)
+	ConstraintTreeVisited(w Visited) ConstraintNode
+}
+
+// like Walk, but carrying w down to v's children
+func WalkVisited(v Validator, f func(Validator), w Visited) {
+	if t, k := v.(VisitWalker); k {
+		t.WalkVisited(f, w)
+		return
+	}
+	v.Walk(f)
+}
+
+// like ConstraintTree, but carrying w down to v's children
+func ConstraintTreeVisited(v Validator, w Visited) ConstraintNode {
+	if t, k := v.(VisitWalker); k {
+		return t.ConstraintTreeVisited(w)
+	}
+	return v.ConstraintTree()
+}
+
+// appends ks to a copy of p, so sibling paths don't share memory
+func appendField(p []string, ks ...string) []string {
+	c := make([]string, len(p), len(p)+len(ks))
+	copy(c, p)
+	return append(c, ks...)
+}
+
+var packagePath = reflect.TypeOf(AnythingValidator{}).PkgPath()
+
+// Object, Map and Array hand one field slice to all of their children,
+// changing only its last element, and leave it to the error of a child that
+// fails. that's only safe with the validators of this package, which keep
+// field nowhere but in the errors they return. Lambdas and validators
+// defined elsewhere get a copy of their own
+func ownField(v Validator) bool {
+	switch v.(type) {
+	case Lambda, CtxLambda:
+		return false
+	}
+	t := reflect.TypeOf(v)
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return t.PkgPath() == packagePath
+}
+
+// copies every field in e, for validators that keep errors beyond the call
+// that returned them. a child's error may be dropped, by Or say, and the
+// slice it refers to handed to the next child
+func detachFields(e *Error) *Error {
+	return prefixFields([]string{}, e)
+}
+
 type Lambda func(v interface{}, f []string) *Error
 
 func (l Lambda) Validate(v interface{}, f []string) *Error {
@@ -52,10 +373,58 @@ func (l Lambda) Traverse(v interface{}, f func(interface{}, Validator)) {
 	f(v, l)
 }
 
+func (l Lambda) Walk(f func(Validator)) {
+	f(l)
+}
+
 func (l Lambda) ConstraintTree() ConstraintNode {
 	return ConstraintNode{`lambda`, nil}
 }
 
+// a Lambda for the validators of this package, which is handed the field
+// slice of Object, Map and Array as it is
+type lambda func(v interface{}, f []string) *Error
+
+func (l lambda) Validate(v interface{}, f []string) *Error {
+	return l(v, f)
+}
+
+func (l lambda) Traverse(v interface{}, f func(interface{}, Validator)) {
+	f(v, l)
+}
+
+func (l lambda) Walk(f func(Validator)) {
+	f(l)
+}
+
+func (l lambda) ConstraintTree() ConstraintNode {
+	return ConstraintNode{`lambda`, nil}
+}
+
+// a Lambda receiving the context passed to ValidateCtx, or
+// context.Background() when called through Validate
+type CtxLambda func(ctx context.Context, v interface{}, f []string) *Error
+
+func (l CtxLambda) Validate(v interface{}, f []string) *Error {
+	return l(context.Background(), v, f)
+}
+
+func (l CtxLambda) ValidateCtx(ctx context.Context, v interface{}, f []string) *Error {
+	return l(ctx, v, f)
+}
+
+func (l CtxLambda) Traverse(v interface{}, f func(interface{}, Validator)) {
+	f(v, l)
+}
+
+func (l CtxLambda) Walk(f func(Validator)) {
+	f(l)
+}
+
+func (l CtxLambda) ConstraintTree() ConstraintNode {
+	return ConstraintNode{`lambda`, nil}
+}
+
 type AnythingValidator struct{}
 
 func Anything() Validator {
@@ -70,6 +439,10 @@ func (a AnythingValidator) Traverse(v interface{}, f func(interface{}, Validator
 	f(v, a)
 }
 
+func (a AnythingValidator) Walk(f func(Validator)) {
+	f(a)
+}
+
 func (a AnythingValidator) ConstraintTree() ConstraintNode {
 	return ConstraintNode{`true`, nil}
 }
@@ -91,6 +464,10 @@ func (a StringValidator) Traverse(v interface{}, f func(interface{}, Validator))
 	f(v, a)
 }
 
+func (a StringValidator) Walk(f func(Validator)) {
+	f(a)
+}
+
 func (a StringValidator) ConstraintTree() ConstraintNode {
 	return ConstraintNode{`typeof(v)==="string"`, nil}
 }
@@ -112,6 +489,10 @@ func (a NumberValidator) Traverse(v interface{}, f func(interface{}, Validator))
 	f(v, a)
 }
 
+func (a NumberValidator) Walk(f func(Validator)) {
+	f(a)
+}
+
 func (a NumberValidator) Theorem(path []string) string {
 	return strings.Join(path, ".") + ": number"
 }
@@ -137,6 +518,10 @@ func (a BooleanValidator) Traverse(v interface{}, f func(interface{}, Validator)
 	f(v, a)
 }
 
+func (a BooleanValidator) Walk(f func(Validator)) {
+	f(a)
+}
+
 func (a BooleanValidator) ConstraintTree() ConstraintNode {
 	return ConstraintNode{`typeof(v)==="boolean"`, nil}
 }
@@ -158,6 +543,10 @@ func (a NullValidator) Traverse(v interface{}, f func(interface{}, Validator)) {
 	f(v, a)
 }
 
+func (a NullValidator) Walk(f func(Validator)) {
+	f(a)
+}
+
 func (a NullValidator) ConstraintTree() ConstraintNode {
 	return ConstraintNode{`v===null`, nil}
 }
@@ -175,8 +564,12 @@ func And(vs ...Validator) Validator {
 }
 
 func (a AndValidator) Validate(v interface{}, f []string) *Error {
+	return a.ValidateCtx(context.Background(), v, f)
+}
+
+func (a AndValidator) ValidateCtx(ctx context.Context, v interface{}, f []string) *Error {
 	for _, b := range a {
-		if e := b.Validate(v, f); e != nil {
+		if e := ValidateCtx(ctx, b, v, f); e != nil {
 			return e
 		}
 	}
@@ -193,10 +586,31 @@ func (a AndValidator) Traverse(v interface{}, f func(interface{}, Validator)) {
 	}
 }
 
+func (a AndValidator) TraversePath(v interface{}, p []string, f func(interface{}, []string, Validator)) {
+	for _, b := range a {
+		TraversePath(b, v, p, f)
+	}
+}
+
+func (a AndValidator) Walk(f func(Validator)) {
+	a.WalkVisited(f, Visited{})
+}
+
+func (a AndValidator) WalkVisited(f func(Validator), w Visited) {
+	f(a)
+	for _, b := range a {
+		WalkVisited(b, f, w)
+	}
+}
+
 func (a AndValidator) ConstraintTree() ConstraintNode {
+	return a.ConstraintTreeVisited(Visited{})
+}
+
+func (a AndValidator) ConstraintTreeVisited(w Visited) ConstraintNode {
 	s := ConstraintNode{"true", nil}
 	for _, a := range a {
-		s = MergeConstraintTrees(s, a.ConstraintTree(), func(a, b Constraint) Constraint {
+		s = MergeConstraintTrees(s, ConstraintTreeVisited(a, w), func(a, b Constraint) Constraint {
 			return a.(string) + " && " + b.(string)
 		})
 	}
@@ -216,14 +630,18 @@ func Or(vs ...Validator) Validator {
 }
 
 func (b OrValidator) Validate(v interface{}, f []string) *Error {
+	return b.ValidateCtx(context.Background(), v, f)
+}
+
+func (b OrValidator) ValidateCtx(ctx context.Context, v interface{}, f []string) *Error {
 	ae := make([]*Error, 0, len(b))
 	for _, a := range b {
-		e := a.Validate(v, f)
+		e := ValidateCtx(ctx, a, v, f)
 		if e == NoError {
 			return NoError
 		}
 		if e.Label == "or" {
-			ae = append(ae, e.Context.([]*Error)...)
+			ae = append(ae, e.Children()...)
 		} else {
 			ae = append(ae, e)
 		}
@@ -248,404 +666,4937 @@ func (a OrValidator) Traverse(v interface{}, f func(interface{}, Validator)) {
 	}
 }
 
+func (a OrValidator) TraversePath(v interface{}, p []string, f func(interface{}, []string, Validator)) {
+	for _, b := range a {
+		TraversePath(b, v, p, f)
+	}
+}
+
+func (a OrValidator) Walk(f func(Validator)) {
+	a.WalkVisited(f, Visited{})
+}
+
+func (a OrValidator) WalkVisited(f func(Validator), w Visited) {
+	f(a)
+	for _, b := range a {
+		WalkVisited(b, f, w)
+	}
+}
+
 func (a OrValidator) ConstraintTree() ConstraintNode {
+	return a.ConstraintTreeVisited(Visited{})
+}
+
+func (a OrValidator) ConstraintTreeVisited(w Visited) ConstraintNode {
 	s := ConstraintNode{"false", nil}
 	for _, a := range a {
-		s = MergeConstraintTrees(s, a.ConstraintTree(), func(a, b Constraint) Constraint {
+		s = MergeConstraintTrees(s, ConstraintTreeVisited(a, w), func(a, b Constraint) Constraint {
 			return a.(string) + " || " + b.(string)
 		})
 	}
 	return s
 }
 
-type CaseValidator map[string]Validator
-
-func Case(d map[string]Validator) Validator {
-	return CaseValidator(d)
-}
+// like Or, but when an object fails every branch only the error of the
+// branch it came closest to, by number of leaf errors, is returned. the
+// first such branch wins a tie. for tagged unions of Objects this names the
+// few fields that are off instead of listing every variant's complaints.
+// leaf counts only compare between Objects, so unless every branch is one
+// it's just Or
+type BestMatchOrValidator []Validator
 
-func (d CaseValidator) Validate(v interface{}, f []string) *Error {
-	o, k := v.(map[string]interface{})
-	if !k {
-		return &Error{"value_must_be_object", f, nil}
-	}
-	if len(o) != 1 {
-		return &Error{"object_must_have_exactly_one_key", f, nil}
+func BestMatchOr(vs ...Validator) Validator {
+	if len(vs) == 0 {
+		panic("or of 0 conditions")
 	}
-	c := ""
-	for k, _ := range o {
-		c = k
+	if len(vs) == 1 {
+		return vs[0]
 	}
-	vd, k := d[c]
-	if !k {
-		return &Error{"case_not_defined", f, c}
+	for _, v := range vs {
+		if _, k := v.(ObjectValidator); !k {
+			return OrValidator(vs)
+		}
 	}
-	tv := o[c]
-	return vd.Validate(tv, append(f, c))
+	return BestMatchOrValidator(vs)
 }
 
-func (a CaseValidator) Structure() map[string]Validator {
-	return (map[string]Validator)(a)
+func (b BestMatchOrValidator) Validate(v interface{}, f []string) *Error {
+	return b.ValidateCtx(context.Background(), v, f)
 }
 
-func (a CaseValidator) Traverse(v interface{}, f func(interface{}, Validator)) {
-	c, o := "", v.(map[string]interface{})
-	for k, _ := range o {
-		c = k
+func (b BestMatchOrValidator) ValidateCtx(ctx context.Context, v interface{}, f []string) *Error {
+	if _, k := v.(map[string]interface{}); !k {
+		return OrValidator(b).ValidateCtx(ctx, v, f)
+	}
+	var be *Error
+	bn := 0
+	for _, a := range b {
+		e := ValidateCtx(ctx, a, v, f)
+		if e == NoError {
+			return NoError
+		}
+		if n := len(e.Leaves()); be == nil || n < bn {
+			be, bn = e, n
+		}
 	}
-	a[c].Traverse(o[c], f)
+	return be
 }
 
-func (a CaseValidator) ConstraintTree() ConstraintNode {
-	c := ConstraintNode{`typeof(v)==="object" && v.keys().length===1 && [<cases>].indexOf(v.keys()[0]) > -1 `, make(map[string]ConstraintNode, len(a))}
-	for k, a := range a {
-		c.Children[k] = a.ConstraintTree()
-	}
-	return c
+func (a BestMatchOrValidator) Validators() []Validator {
+	return []Validator(a)
 }
 
-type ObjectValidator map[string]Validator
+func (a BestMatchOrValidator) Traverse(v interface{}, f func(interface{}, Validator)) {
+	OrValidator(a).Traverse(v, f)
+}
 
-func Object(d map[string]Validator) Validator {
-	return ObjectValidator(d)
+func (a BestMatchOrValidator) TraversePath(v interface{}, p []string, f func(interface{}, []string, Validator)) {
+	OrValidator(a).TraversePath(v, p, f)
 }
 
-func (d ObjectValidator) Validate(v interface{}, f []string) *Error {
-	o, k := v.(map[string]interface{})
-	if !k {
-		return &Error{"value_must_be_object", f, nil}
-	}
-	ae := make([]*Error, 0, len(d))
-	for k, _ := range o {
-		if _, ok := d[k]; !ok {
-			ae = append(ae, &Error{"unexpected_object_key", f, k})
-		}
-	}
-	for k, a := range d {
-		u, x := o[k]
-		if !x {
-			ae = append(ae, &Error{"missing_object_key", f, k})
-			continue
-		}
-		if e := a.Validate(u, append(f, k)); e != nil {
-			ae = append(ae, e)
-		}
+func (a BestMatchOrValidator) Walk(f func(Validator)) {
+	a.WalkVisited(f, Visited{})
+}
+
+func (a BestMatchOrValidator) WalkVisited(f func(Validator), w Visited) {
+	f(a)
+	for _, b := range a {
+		WalkVisited(b, f, w)
 	}
-	if len(ae) == 0 {
+}
+
+func (a BestMatchOrValidator) ConstraintTree() ConstraintNode {
+	return a.ConstraintTreeVisited(Visited{})
+}
+
+func (a BestMatchOrValidator) ConstraintTreeVisited(w Visited) ConstraintNode {
+	return ConstraintTreeVisited(OrValidator(a), w)
+}
+
+// unlike Or(e, Null()), errors of e are returned as they are instead of being
+// part of an "or" that also complains about the value not being null
+type NullableValidator struct {
+	e Validator
+}
+
+func Nullable(e Validator) Validator {
+	return NullableValidator{e}
+}
+
+func (a NullableValidator) Validate(v interface{}, f []string) *Error {
+	return a.ValidateCtx(context.Background(), v, f)
+}
+
+func (a NullableValidator) ValidateCtx(ctx context.Context, v interface{}, f []string) *Error {
+	if v == nil {
 		return NoError
 	}
-	return &Error{"and", []string{}, ae}
+	return ValidateCtx(ctx, a.e, v, f)
 }
 
-func (a ObjectValidator) Structure() map[string]Validator {
-	return (map[string]Validator)(a)
+func (a NullableValidator) Validator() Validator {
+	return a.e
 }
 
-func (a ObjectValidator) Traverse(v interface{}, f func(interface{}, Validator)) {
-	for k, v := range v.(map[string]interface{}) {
-		a[k].Traverse(v, f)
+func (a NullableValidator) Traverse(v interface{}, f func(interface{}, Validator)) {
+	if v == nil {
+		f(v, a)
+		return
 	}
+	a.e.Traverse(v, f)
 }
 
-func (a ObjectValidator) ConstraintTree() ConstraintNode {
-	c := ConstraintNode{`typeof(v)==="object" && v.keys()===[<keys>]`, make(map[string]ConstraintNode, len(a))}
-	for k, a := range a {
-		c.Children[k] = a.ConstraintTree()
+func (a NullableValidator) TraversePath(v interface{}, p []string, f func(interface{}, []string, Validator)) {
+	if v == nil {
+		f(v, p, a)
+		return
 	}
-	return c
+	TraversePath(a.e, v, p, f)
 }
 
-type MapValidator struct {
-	e Validator
+func (a NullableValidator) Walk(f func(Validator)) {
+	a.WalkVisited(f, Visited{})
 }
 
-func Map(e Validator) Validator {
-	return MapValidator{e}
+func (a NullableValidator) WalkVisited(f func(Validator), w Visited) {
+	f(a)
+	WalkVisited(a.e, f, w)
 }
 
-func (a MapValidator) Validate(v interface{}, f []string) *Error {
-	o, k := v.(map[string]interface{})
-	if !k {
-		return &Error{"value_must_be_object", f, nil}
+func (a NullableValidator) ConstraintTree() ConstraintNode {
+	return a.ConstraintTreeVisited(Visited{})
+}
+
+func (a NullableValidator) ConstraintTreeVisited(w Visited) ConstraintNode {
+	return MergeConstraintTrees(ConstraintNode{`v===null`, nil}, ConstraintTreeVisited(a.e, w), func(a, b Constraint) Constraint {
+		return a.(string) + " || (" + b.(string) + ")"
+	})
+}
+
+// applies t only to values p holds for, anything else passes
+type WhenValidator struct {
+	p func(interface{}) bool
+	t Validator
+}
+
+func When(p func(v interface{}) bool, t Validator) Validator {
+	return WhenValidator{p, t}
+}
+
+func (a WhenValidator) Validator() Validator {
+	return a.t
+}
+
+func (a WhenValidator) Validate(v interface{}, f []string) *Error {
+	return a.ValidateCtx(context.Background(), v, f)
+}
+
+func (a WhenValidator) ValidateCtx(ctx context.Context, v interface{}, f []string) *Error {
+	if !a.p(v) {
+		return NoError
 	}
-	ae := make([]*Error, 0, 8)
-	for k, u := range o {
-		if e := a.e.Validate(u, append(f, k)); e != nil {
-			ae = append(ae, e)
-		}
+	return ValidateCtx(ctx, a.t, v, f)
+}
+
+func (a WhenValidator) Traverse(v interface{}, f func(interface{}, Validator)) {
+	if !a.p(v) {
+		f(v, a)
+		return
 	}
-	if len(ae) == 0 {
-		return NoError
+	a.t.Traverse(v, f)
+}
+
+func (a WhenValidator) TraversePath(v interface{}, p []string, f func(interface{}, []string, Validator)) {
+	if !a.p(v) {
+		f(v, p, a)
+		return
 	}
-	return &Error{"and", []string{}, ae}
+	TraversePath(a.t, v, p, f)
 }
-func (a MapValidator) Validator() Validator {
+
+func (a WhenValidator) Walk(f func(Validator)) {
+	a.WalkVisited(f, Visited{})
+}
+
+func (a WhenValidator) WalkVisited(f func(Validator), w Visited) {
+	f(a)
+	WalkVisited(a.t, f, w)
+}
+
+func (a WhenValidator) ConstraintTree() ConstraintNode {
+	return a.ConstraintTreeVisited(Visited{})
+}
+
+func (a WhenValidator) ConstraintTreeVisited(w Visited) ConstraintNode {
+	return MergeConstraintTrees(ConstraintNode{`!<predicate>(v)`, nil}, ConstraintTreeVisited(a.t, w), func(a, b Constraint) Constraint {
+		return a.(string) + " || (" + b.(string) + ")"
+	})
+}
+
+// marks a key of an Object that may be left out. when present, its value
+// must satisfy e. outside of an Object it's just e
+type OptionalValidator struct {
+	e Validator
+}
+
+func Optional(e Validator) Validator {
+	return OptionalValidator{e}
+}
+
+// marks a key of an Object that may be left out or be null, both meaning
+// "not provided"; e only sees values that aren't null
+func OptionalNullable(e Validator) Validator {
+	return OptionalValidator{Nullable(e)}
+}
+
+func (a OptionalValidator) Validate(v interface{}, f []string) *Error {
+	return a.ValidateCtx(context.Background(), v, f)
+}
+
+func (a OptionalValidator) ValidateCtx(ctx context.Context, v interface{}, f []string) *Error {
+	return ValidateCtx(ctx, a.e, v, f)
+}
+
+func (a OptionalValidator) Validator() Validator {
 	return a.e
 }
 
-func (a MapValidator) Traverse(v interface{}, f func(interface{}, Validator)) {
-	for _, v := range v.(map[string]interface{}) {
-		a.e.Traverse(v, f)
+func (a OptionalValidator) Traverse(v interface{}, f func(interface{}, Validator)) {
+	a.e.Traverse(v, f)
+}
+
+func (a OptionalValidator) TraversePath(v interface{}, p []string, f func(interface{}, []string, Validator)) {
+	TraversePath(a.e, v, p, f)
+}
+
+func (a OptionalValidator) Walk(f func(Validator)) {
+	a.WalkVisited(f, Visited{})
+}
+
+func (a OptionalValidator) WalkVisited(f func(Validator), w Visited) {
+	f(a)
+	WalkVisited(a.e, f, w)
+}
+
+func (a OptionalValidator) ConstraintTree() ConstraintNode {
+	return a.ConstraintTreeVisited(Visited{})
+}
+
+func (a OptionalValidator) ConstraintTreeVisited(w Visited) ConstraintNode {
+	return MergeConstraintTrees(ConstraintNode{`v===undefined`, nil}, ConstraintTreeVisited(a.e, w), func(a, b Constraint) Constraint {
+		return a.(string) + " || (" + b.(string) + ")"
+	})
+}
+
+// whether v is an Optional, possibly inside validators that only wrap it
+// without changing what it accepts, like Annotate or Tee
+func isOptional(v Validator) bool {
+	switch a := v.(type) {
+	case OptionalValidator:
+		return true
+	case AnnotatedValidator:
+		return isOptional(a.e)
+	case TeeValidator:
+		return isOptional(a.e)
+	case RecoverValidator:
+		return isOptional(a.e)
+	case *MemoizedValidator:
+		return isOptional(a.e)
+	case MaxDepthValidator:
+		return isOptional(a.e)
+	case TimeBudgetValidator:
+		return isOptional(a.e)
+	case PrefixFieldsValidator:
+		return isOptional(a.e)
+	case coverageProbe:
+		return isOptional(a.e)
 	}
+	return false
 }
 
-func (a MapValidator) ConstraintTree() ConstraintNode {
-	c := ConstraintNode{`typeof(v)==="object"`, make(map[string]ConstraintNode, 8)}
-	c.Children["*"] = a.e.ConstraintTree()
-	return c
+// attaches metadata like "deprecated" or "title" to e without changing how
+// it validates. the metadata shows in ConstraintTree and Describe
+type AnnotatedValidator struct {
+	e Validator
+	m map[string]interface{}
 }
 
-type ArrayValidator struct {
+func Annotate(e Validator, m map[string]interface{}) Validator {
+	return AnnotatedValidator{e, m}
+}
+
+func (a AnnotatedValidator) Validate(v interface{}, f []string) *Error {
+	return a.ValidateCtx(context.Background(), v, f)
+}
+
+func (a AnnotatedValidator) ValidateCtx(ctx context.Context, v interface{}, f []string) *Error {
+	return ValidateCtx(ctx, a.e, v, f)
+}
+
+func (a AnnotatedValidator) Validator() Validator {
+	return a.e
+}
+
+func (a AnnotatedValidator) Annotations() map[string]interface{} {
+	return a.m
+}
+
+func (a AnnotatedValidator) Traverse(v interface{}, f func(interface{}, Validator)) {
+	a.e.Traverse(v, f)
+}
+
+func (a AnnotatedValidator) TraversePath(v interface{}, p []string, f func(interface{}, []string, Validator)) {
+	TraversePath(a.e, v, p, f)
+}
+
+func (a AnnotatedValidator) Walk(f func(Validator)) {
+	a.WalkVisited(f, Visited{})
+}
+
+func (a AnnotatedValidator) WalkVisited(f func(Validator), w Visited) {
+	f(a)
+	WalkVisited(a.e, f, w)
+}
+
+// prefixes the root constraint with the metadata as a JSON comment
+func (a AnnotatedValidator) ConstraintTree() ConstraintNode {
+	return a.ConstraintTreeVisited(Visited{})
+}
+
+func (a AnnotatedValidator) ConstraintTreeVisited(w Visited) ConstraintNode {
+	b, _ := json.Marshal(a.m)
+	return MergeConstraintTrees(ConstraintNode{`/*` + string(b) + `*/`, nil}, ConstraintTreeVisited(a.e, w), func(a, b Constraint) Constraint {
+		return a.(string) + " " + b.(string)
+	})
+}
+
+// remembers the results of e for the last n distinct values, keyed by a
+// hash of the field path and the value, dynamic types included. only
+// correct when e is pure: a CtxLambda or Lambda depending on anything but
+// its arguments would have its first answer replayed. hits return the same
+// *Error every time, so callers mustn't modify it. values holding types
+// encoding/json doesn't decode into aren't cached
+type MemoizedValidator struct {
 	e Validator
+	n int
+	m sync.Mutex
+	l *list.List
+	c map[[sha256.Size]byte]*list.Element
 }
 
-func Array(e Validator) Validator {
-	return ArrayValidator{e}
+type memoEntry struct {
+	k [sha256.Size]byte
+	e *Error
 }
 
-func (a ArrayValidator) Validate(v interface{}, f []string) *Error {
-	o, k := v.([]interface{})
+func Memoize(e Validator) Validator {
+	return MemoizeN(e, 1024)
+}
+
+func MemoizeN(e Validator, n int) Validator {
+	if n < 1 {
+		panic("MemoizeN: n < 1")
+	}
+	return &MemoizedValidator{e: e, n: n, l: list.New(), c: make(map[[sha256.Size]byte]*list.Element, n)}
+}
+
+func (a *MemoizedValidator) Validator() Validator {
+	return a.e
+}
+
+func (a *MemoizedValidator) Validate(v interface{}, f []string) *Error {
+	return a.ValidateCtx(context.Background(), v, f)
+}
+
+func (a *MemoizedValidator) ValidateCtx(ctx context.Context, v interface{}, f []string) *Error {
+	k, x := memoKey(f, v)
+	if !x {
+		return ValidateCtx(ctx, a.e, v, f)
+	}
+	a.m.Lock()
+	if c, x := a.c[k]; x {
+		a.l.MoveToFront(c)
+		a.m.Unlock()
+		return c.Value.(memoEntry).e
+	}
+	a.m.Unlock()
+	e := detachFields(ValidateCtx(ctx, a.e, v, f))
+	if ctx.Err() != nil {
+		return e
+	}
+	a.m.Lock()
+	defer a.m.Unlock()
+	if _, x := a.c[k]; !x {
+		a.c[k] = a.l.PushFront(memoEntry{k, e})
+		if a.l.Len() > a.n {
+			delete(a.c, a.l.Remove(a.l.Back()).(memoEntry).k)
+		}
+	}
+	return e
+}
+
+// float64(5), json.Number("5") and int(5) would all encode as 5 in JSON but
+// validators tell them apart, so every value is tagged with its type.
+// strings and containers are prefixed with their length, so no two values
+// share an encoding
+func memoKey(f []string, v interface{}) ([sha256.Size]byte, bool) {
+	b := strconv.AppendInt(make([]byte, 0, 256), int64(len(f)), 10)
+	for _, s := range f {
+		b = appendMemoString(b, s)
+	}
+	b, k := appendMemoValue(b, v)
 	if !k {
-		return &Error{"value_must_be_array", f, nil}
+		return [sha256.Size]byte{}, false
 	}
-	ae := make([]*Error, 0, 8)
-	for i, u := range o {
-		if e := a.e.Validate(u, append(f, strconv.Itoa(i))); e != nil {
-			ae = append(ae, e)
+	return sha256.Sum256(b), true
+}
+
+func appendMemoString(b []byte, s string) []byte {
+	b = strconv.AppendInt(b, int64(len(s)), 10)
+	return append(append(b, ':'), s...)
+}
+
+func appendMemoValue(b []byte, v interface{}) ([]byte, bool) {
+	switch t := v.(type) {
+	case nil:
+		return append(b, 'z'), true
+	case bool:
+		if t {
+			return append(b, 't'), true
+		}
+		return append(b, 'f'), true
+	case float64:
+		b = strconv.AppendUint(append(b, 'n'), math.Float64bits(t), 16)
+		return append(b, ';'), true
+	case json.Number:
+		return appendMemoString(append(b, 'N'), string(t)), true
+	case string:
+		return appendMemoString(append(b, 's'), t), true
+	case []interface{}:
+		b = strconv.AppendInt(append(b, 'a'), int64(len(t)), 10)
+		b = append(b, ':')
+		for _, u := range t {
+			var k bool
+			if b, k = appendMemoValue(b, u); !k {
+				return b, false
+			}
 		}
+		return b, true
+	case map[string]interface{}:
+		ks := make([]string, 0, len(t))
+		for k, _ := range t {
+			ks = append(ks, k)
+		}
+		sort.Strings(ks)
+		b = strconv.AppendInt(append(b, 'o'), int64(len(t)), 10)
+		b = append(b, ':')
+		for _, k := range ks {
+			var x bool
+			if b, x = appendMemoValue(appendMemoString(b, k), t[k]); !x {
+				return b, false
+			}
+		}
+		return b, true
 	}
-	if len(ae) == 0 {
-		return NoError
+	return b, false
+}
+
+func (a *MemoizedValidator) Traverse(v interface{}, f func(interface{}, Validator)) {
+	a.e.Traverse(v, f)
+}
+
+func (a *MemoizedValidator) TraversePath(v interface{}, p []string, f func(interface{}, []string, Validator)) {
+	TraversePath(a.e, v, p, f)
+}
+
+func (a *MemoizedValidator) Walk(f func(Validator)) {
+	a.WalkVisited(f, Visited{})
+}
+
+func (a *MemoizedValidator) WalkVisited(f func(Validator), w Visited) {
+	f(a)
+	WalkVisited(a.e, f, w)
+}
+
+func (a *MemoizedValidator) ConstraintTree() ConstraintNode {
+	return a.ConstraintTreeVisited(Visited{})
+}
+
+func (a *MemoizedValidator) ConstraintTreeVisited(w Visited) ConstraintNode {
+	return ConstraintTreeVisited(a.e, w)
+}
+
+// rejects values nested more than n levels deep in objects and arrays, to
+// bound the work done on hostile input. levels are counted by Object, Map,
+// EnumCountMap, Array, Case and NestedMatchesKind as they descend, also
+// when reached through Recursion; validators not taking a context (Lambda
+// and the like) end the count below them
+type MaxDepthValidator struct {
+	e Validator
+	n int
+}
+
+type depthKey struct{}
+
+type depth struct {
+	n, d int
+}
+
+func MaxDepth(e Validator, n int) Validator {
+	if n < 0 {
+		panic("MaxDepth: n < 0")
 	}
-	return &Error{"and", []string{}, ae}
+	return MaxDepthValidator{e, n}
 }
-func (a ArrayValidator) Validator() Validator {
+
+func (a MaxDepthValidator) Max() int {
+	return a.n
+}
+
+func (a MaxDepthValidator) Validator() Validator {
 	return a.e
 }
 
-func (a ArrayValidator) Traverse(v interface{}, f func(interface{}, Validator)) {
-	for _, v := range v.([]interface{}) {
-		a.e.Traverse(v, f)
+func (a MaxDepthValidator) Validate(v interface{}, f []string) *Error {
+	return a.ValidateCtx(context.Background(), v, f)
+}
+
+func (a MaxDepthValidator) ValidateCtx(ctx context.Context, v interface{}, f []string) *Error {
+	return ValidateCtx(context.WithValue(ctx, depthKey{}, depth{a.n, 0}), a.e, v, f)
+}
+
+func (a MaxDepthValidator) Traverse(v interface{}, f func(interface{}, Validator)) {
+	a.e.Traverse(v, f)
+}
+
+func (a MaxDepthValidator) TraversePath(v interface{}, p []string, f func(interface{}, []string, Validator)) {
+	TraversePath(a.e, v, p, f)
+}
+
+func (a MaxDepthValidator) Walk(f func(Validator)) {
+	a.WalkVisited(f, Visited{})
+}
+
+func (a MaxDepthValidator) WalkVisited(f func(Validator), w Visited) {
+	f(a)
+	WalkVisited(a.e, f, w)
+}
+
+func (a MaxDepthValidator) ConstraintTree() ConstraintNode {
+	return a.ConstraintTreeVisited(Visited{})
+}
+
+func (a MaxDepthValidator) ConstraintTreeVisited(w Visited) ConstraintNode {
+	return ConstraintTreeVisited(a.e, w)
+}
+
+// called by composites before validating the contents of an object or
+// array at f; fails once the limit set by MaxDepth is reached
+func descend(ctx context.Context, f []string) (context.Context, *Error) {
+	d, k := ctx.Value(depthKey{}).(depth)
+	if !k {
+		return ctx, NoError
 	}
+	if d.d >= d.n {
+		return ctx, &Error{"max_depth_exceeded", f, d.n}
+	}
+	return context.WithValue(ctx, depthKey{}, depth{d.n, d.d + 1}), NoError
 }
 
-func (a ArrayValidator) ConstraintTree() ConstraintNode {
-	c := ConstraintNode{`typeof(v)==="array"`, make(map[string]ConstraintNode, 8)}
-	c.Children["*"] = a.e.ConstraintTree()
-	return c
+// puts b in front of the field of every error of e, aggregates and their
+// children included, e.g. to root errors about an extracted sub-document at
+// its place in the request. the errors are copied, not modified
+type PrefixFieldsValidator struct {
+	b []string
+	e Validator
 }
 
-type RegexValidator struct {
-	x, l string
-	i, m bool
+func PrefixFields(b []string, e Validator) Validator {
+	return PrefixFieldsValidator{b, e}
+}
+
+func (a PrefixFieldsValidator) Prefix() []string {
+	return a.b
+}
+
+func (a PrefixFieldsValidator) Validator() Validator {
+	return a.e
+}
+
+func (a PrefixFieldsValidator) Validate(v interface{}, f []string) *Error {
+	return a.ValidateCtx(context.Background(), v, f)
+}
+
+func (a PrefixFieldsValidator) ValidateCtx(ctx context.Context, v interface{}, f []string) *Error {
+	return prefixFields(a.b, ValidateCtx(ctx, a.e, v, f))
+}
+
+func prefixFields(b []string, e *Error) *Error {
+	if e == nil {
+		return NoError
+	}
+	c := e.Context
+	switch t := c.(type) {
+	case []*Error:
+		cs := e.Children()
+		if cs == nil {
+			break
+		}
+		ps := make([]*Error, len(cs))
+		for i, d := range cs {
+			ps[i] = prefixFields(b, d)
+		}
+		c = ps
+	case *Error:
+		if e.Label == "array_item" {
+			c = prefixFields(b, t)
+		}
+	}
+	return &Error{e.Label, appendField(b, e.Field...), c}
+}
+
+func (a PrefixFieldsValidator) Traverse(v interface{}, f func(interface{}, Validator)) {
+	a.e.Traverse(v, f)
+}
+
+func (a PrefixFieldsValidator) TraversePath(v interface{}, p []string, f func(interface{}, []string, Validator)) {
+	TraversePath(a.e, v, appendField(a.b, p...), f)
+}
+
+func (a PrefixFieldsValidator) Walk(f func(Validator)) {
+	a.WalkVisited(f, Visited{})
+}
+
+func (a PrefixFieldsValidator) WalkVisited(f func(Validator), w Visited) {
+	f(a)
+	WalkVisited(a.e, f, w)
+}
+
+func (a PrefixFieldsValidator) ConstraintTree() ConstraintNode {
+	return a.ConstraintTreeVisited(Visited{})
+}
+
+func (a PrefixFieldsValidator) ConstraintTreeVisited(w Visited) ConstraintNode {
+	return ConstraintTreeVisited(a.e, w)
+}
+
+// turns a panic in e, like a type assertion on data e doesn't expect, into
+// an "internal_validation_panic" error carrying the recovered value as a
+// string. a panic while traversing stops the traversal silently
+type RecoverValidator struct {
+	e Validator
+}
+
+func Recover(e Validator) Validator {
+	return RecoverValidator{e}
+}
+
+func (a RecoverValidator) Validator() Validator {
+	return a.e
+}
+
+func (a RecoverValidator) Validate(v interface{}, f []string) *Error {
+	return a.ValidateCtx(context.Background(), v, f)
+}
+
+func (a RecoverValidator) ValidateCtx(ctx context.Context, v interface{}, f []string) (e *Error) {
+	defer func() {
+		if r := recover(); r != nil {
+			e = &Error{"internal_validation_panic", f, fmt.Sprint(r)}
+		}
+	}()
+	return ValidateCtx(ctx, a.e, v, f)
+}
+
+func (a RecoverValidator) Traverse(v interface{}, f func(interface{}, Validator)) {
+	defer func() {
+		recover()
+	}()
+	a.e.Traverse(v, f)
+}
+
+func (a RecoverValidator) TraversePath(v interface{}, p []string, f func(interface{}, []string, Validator)) {
+	defer func() {
+		recover()
+	}()
+	TraversePath(a.e, v, p, f)
+}
+
+func (a RecoverValidator) Walk(f func(Validator)) {
+	a.WalkVisited(f, Visited{})
+}
+
+func (a RecoverValidator) WalkVisited(f func(Validator), w Visited) {
+	f(a)
+	WalkVisited(a.e, f, w)
+}
+
+func (a RecoverValidator) ConstraintTree() ConstraintNode {
+	return a.ConstraintTreeVisited(Visited{})
+}
+
+func (a RecoverValidator) ConstraintTreeVisited(w Visited) ConstraintNode {
+	return ConstraintTreeVisited(a.e, w)
+}
+
+// runs e and passes the value, its field and e's result, nil or the whole
+// aggregate error, to h before returning that result unchanged. h gets
+// copies of the field and the error's fields, so it may keep them
+type TeeValidator struct {
+	e Validator
+	h func(interface{}, []string, *Error)
+}
+
+func Tee(e Validator, h func(value interface{}, field []string, err *Error)) Validator {
+	return TeeValidator{e, h}
+}
+
+func (a TeeValidator) Validator() Validator {
+	return a.e
+}
+
+func (a TeeValidator) Validate(v interface{}, f []string) *Error {
+	return a.ValidateCtx(context.Background(), v, f)
+}
+
+func (a TeeValidator) ValidateCtx(ctx context.Context, v interface{}, f []string) *Error {
+	e := detachFields(ValidateCtx(ctx, a.e, v, f))
+	a.h(v, appendField(f), e)
+	return e
+}
+
+func (a TeeValidator) Traverse(v interface{}, f func(interface{}, Validator)) {
+	a.e.Traverse(v, f)
+}
+
+func (a TeeValidator) TraversePath(v interface{}, p []string, f func(interface{}, []string, Validator)) {
+	TraversePath(a.e, v, p, f)
+}
+
+func (a TeeValidator) Walk(f func(Validator)) {
+	a.WalkVisited(f, Visited{})
+}
+
+func (a TeeValidator) WalkVisited(f func(Validator), w Visited) {
+	f(a)
+	WalkVisited(a.e, f, w)
+}
+
+func (a TeeValidator) ConstraintTree() ConstraintNode {
+	return a.ConstraintTreeVisited(Visited{})
+}
+
+func (a TeeValidator) ConstraintTreeVisited(w Visited) ConstraintNode {
+	return ConstraintTreeVisited(a.e, w)
+}
+
+// gives up on e after d. e runs in its own goroutine with a context that's
+// done after d; Object, Map and Array stop early once it is, but any other
+// validator keeps running until it returns and its result is discarded. a
+// panic in e is raised again in the calling goroutine, where Recover can
+// catch it, unless it comes after d and is discarded too
+type TimeBudgetValidator struct {
+	d time.Duration
+	e Validator
+}
+
+func TimeBudget(d time.Duration, e Validator) Validator {
+	return TimeBudgetValidator{d, e}
+}
+
+func (a TimeBudgetValidator) Validate(v interface{}, f []string) *Error {
+	return a.ValidateCtx(context.Background(), v, f)
+}
+
+func (a TimeBudgetValidator) ValidateCtx(ctx context.Context, v interface{}, f []string) *Error {
+	ctx, cancel := context.WithTimeout(ctx, a.d)
+	defer cancel()
+	c, r := make(chan *Error, 1), make(chan interface{}, 1)
+	p := appendField(f) // the goroutine may outlive this call
+	go func() {
+		defer func() {
+			if x := recover(); x != nil {
+				r <- x
+			}
+		}()
+		c <- ValidateCtx(ctx, a.e, v, p)
+	}()
+	select {
+	case e := <-c:
+		return e
+	case x := <-r:
+		panic(x)
+	case <-ctx.Done():
+		return &Error{"validation_timed_out", f, a.d.String()}
+	}
+}
+
+func (a TimeBudgetValidator) Budget() time.Duration {
+	return a.d
+}
+
+func (a TimeBudgetValidator) Validator() Validator {
+	return a.e
+}
+
+func (a TimeBudgetValidator) Traverse(v interface{}, f func(interface{}, Validator)) {
+	a.e.Traverse(v, f)
+}
+
+func (a TimeBudgetValidator) TraversePath(v interface{}, p []string, f func(interface{}, []string, Validator)) {
+	TraversePath(a.e, v, p, f)
+}
+
+func (a TimeBudgetValidator) Walk(f func(Validator)) {
+	a.WalkVisited(f, Visited{})
+}
+
+func (a TimeBudgetValidator) WalkVisited(f func(Validator), w Visited) {
+	f(a)
+	WalkVisited(a.e, f, w)
+}
+
+func (a TimeBudgetValidator) ConstraintTree() ConstraintNode {
+	return a.ConstraintTreeVisited(Visited{})
+}
+
+func (a TimeBudgetValidator) ConstraintTreeVisited(w Visited) ConstraintNode {
+	return ConstraintTreeVisited(a.e, w)
+}
+
+type CaseValidator map[string]Validator
+
+func Case(d map[string]Validator) Validator {
+	return CaseValidator(d)
+}
+
+func (d CaseValidator) Validate(v interface{}, f []string) *Error {
+	return d.ValidateCtx(context.Background(), v, f)
+}
+
+func (d CaseValidator) ValidateCtx(ctx context.Context, v interface{}, f []string) *Error {
+	o, k := v.(map[string]interface{})
+	if !k {
+		return &Error{"value_must_be_object", f, nil}
+	}
+	ctx, e := descend(ctx, f)
+	if e != nil {
+		return e
+	}
+	if len(o) != 1 {
+		return &Error{"object_must_have_exactly_one_key", f, nil}
+	}
+	c := ""
+	for k, _ := range o {
+		c = k
+	}
+	vd, k := d[c]
+	if !k {
+		return &Error{"case_not_defined", f, c}
+	}
+	tv := o[c]
+	return ValidateCtx(ctx, vd, tv, append(f, c))
+}
+
+func (a CaseValidator) Structure() map[string]Validator {
+	return (map[string]Validator)(a)
+}
+
+func (a CaseValidator) Traverse(v interface{}, f func(interface{}, Validator)) {
+	o, _ := v.(map[string]interface{})
+	for k, u := range o {
+		if b, x := a[k]; x && len(o) == 1 {
+			b.Traverse(u, f)
+		}
+	}
+}
+
+func (a CaseValidator) TraversePath(v interface{}, p []string, f func(interface{}, []string, Validator)) {
+	o, _ := v.(map[string]interface{})
+	for k, u := range o {
+		if b, x := a[k]; x && len(o) == 1 {
+			TraversePath(b, u, appendField(p, k), f)
+		}
+	}
+}
+
+func (a CaseValidator) Walk(f func(Validator)) {
+	a.WalkVisited(f, Visited{})
+}
+
+func (a CaseValidator) WalkVisited(f func(Validator), w Visited) {
+	f(a)
+	for _, b := range a {
+		WalkVisited(b, f, w)
+	}
+}
+
+func (a CaseValidator) ConstraintTree() ConstraintNode {
+	return a.ConstraintTreeVisited(Visited{})
+}
+
+func (a CaseValidator) ConstraintTreeVisited(w Visited) ConstraintNode {
+	c := ConstraintNode{`typeof(v)==="object" && v.keys().length===1 && [<cases>].indexOf(v.keys()[0]) > -1 `, make(map[string]ConstraintNode, len(a))}
+	for k, a := range a {
+		c.Children[k] = ConstraintTreeVisited(a, w)
+	}
+	return c
+}
+
+// for objects like {"type":"dog",...}: the value at key k picks the
+// validator that the whole object must satisfy
+type DiscriminatorValidator struct {
+	k string
+	d map[string]Validator
+}
+
+func Discriminator(k string, d map[string]Validator) Validator {
+	return DiscriminatorValidator{k, d}
+}
+
+func (a DiscriminatorValidator) Field() string {
+	return a.k
+}
+
+func (a DiscriminatorValidator) Structure() map[string]Validator {
+	return a.d
+}
+
+func (a DiscriminatorValidator) Validate(v interface{}, f []string) *Error {
+	return a.ValidateCtx(context.Background(), v, f)
+}
+
+func (a DiscriminatorValidator) ValidateCtx(ctx context.Context, v interface{}, f []string) *Error {
+	o, k := v.(map[string]interface{})
+	if !k {
+		return &Error{"value_must_be_object", f, nil}
+	}
+	u, k := o[a.k]
+	if !k {
+		return &Error{"missing_discriminator", f, a.k}
+	}
+	c, _ := u.(string)
+	vd, k := a.d[c]
+	if !k {
+		return &Error{"unknown_discriminator", append(f, a.k), u}
+	}
+	return ValidateCtx(ctx, vd, v, f)
+}
+
+func (a DiscriminatorValidator) Traverse(v interface{}, f func(interface{}, Validator)) {
+	o, _ := v.(map[string]interface{})
+	c, _ := o[a.k].(string)
+	if vd, k := a.d[c]; k {
+		vd.Traverse(v, f)
+	}
+}
+
+func (a DiscriminatorValidator) TraversePath(v interface{}, p []string, f func(interface{}, []string, Validator)) {
+	o, _ := v.(map[string]interface{})
+	c, _ := o[a.k].(string)
+	if vd, k := a.d[c]; k {
+		TraversePath(vd, v, p, f)
+	}
+}
+
+func (a DiscriminatorValidator) Walk(f func(Validator)) {
+	a.WalkVisited(f, Visited{})
+}
+
+func (a DiscriminatorValidator) WalkVisited(f func(Validator), w Visited) {
+	f(a)
+	for _, b := range a.d {
+		WalkVisited(b, f, w)
+	}
+}
+
+func (a DiscriminatorValidator) ConstraintTree() ConstraintNode {
+	return a.ConstraintTreeVisited(Visited{})
+}
+
+func (a DiscriminatorValidator) ConstraintTreeVisited(w Visited) ConstraintNode {
+	c := ConstraintNode{`typeof(v)==="object" && [<cases>].indexOf(v[<field>]) > -1`, make(map[string]ConstraintNode, len(a.d))}
+	for k, a := range a.d {
+		c.Children[k] = ConstraintTreeVisited(a, w)
+	}
+	return c
+}
+
+// for objects like {"kind":"circle","circle":{...}}: the object named by the
+// kind must be present and valid, the objects of all other kinds absent
+type NestedMatchesKindValidator struct {
+	k string
+	d map[string]Validator
+}
+
+func NestedMatchesKind(k string, d map[string]Validator) Validator {
+	return NestedMatchesKindValidator{k, d}
+}
+
+func (a NestedMatchesKindValidator) Validate(v interface{}, f []string) *Error {
+	return a.ValidateCtx(context.Background(), v, f)
+}
+
+func (a NestedMatchesKindValidator) ValidateCtx(ctx context.Context, v interface{}, f []string) *Error {
+	o, k := v.(map[string]interface{})
+	if !k {
+		return &Error{"value_must_be_object", f, nil}
+	}
+	ctx, e := descend(ctx, f)
+	if e != nil {
+		return e
+	}
+	u, x := o[a.k]
+	if !x {
+		return &Error{"missing_object_key", f, a.k}
+	}
+	c, k := u.(string)
+	if !k {
+		return &Error{"value_must_be_string", append(f, a.k), nil}
+	}
+	vd, k := a.d[c]
+	if !k {
+		return &Error{"case_not_defined", append(f, a.k), c}
+	}
+	for n, _ := range a.d {
+		if _, x := o[n]; x && n != c {
+			return &Error{"wrong_nested_object_for_kind", f, map[string]string{"kind": c, "key": n}}
+		}
+	}
+	tv, x := o[c]
+	if !x {
+		return &Error{"missing_object_key", f, c}
+	}
+	return ValidateCtx(ctx, vd, tv, append(f, c))
+}
+
+func (a NestedMatchesKindValidator) Key() string {
+	return a.k
+}
+
+func (a NestedMatchesKindValidator) Structure() map[string]Validator {
+	return a.d
+}
+
+func (a NestedMatchesKindValidator) Traverse(v interface{}, f func(interface{}, Validator)) {
+	o, _ := v.(map[string]interface{})
+	c, _ := o[a.k].(string)
+	if vd, k := a.d[c]; k {
+		vd.Traverse(o[c], f)
+	}
+}
+
+func (a NestedMatchesKindValidator) TraversePath(v interface{}, p []string, f func(interface{}, []string, Validator)) {
+	o, _ := v.(map[string]interface{})
+	c, _ := o[a.k].(string)
+	if vd, k := a.d[c]; k {
+		TraversePath(vd, o[c], appendField(p, c), f)
+	}
+}
+
+func (a NestedMatchesKindValidator) Walk(f func(Validator)) {
+	a.WalkVisited(f, Visited{})
+}
+
+func (a NestedMatchesKindValidator) WalkVisited(f func(Validator), w Visited) {
+	f(a)
+	for _, b := range a.d {
+		WalkVisited(b, f, w)
+	}
+}
+
+func (a NestedMatchesKindValidator) ConstraintTree() ConstraintNode {
+	return a.ConstraintTreeVisited(Visited{})
+}
+
+func (a NestedMatchesKindValidator) ConstraintTreeVisited(w Visited) ConstraintNode {
+	c := ConstraintNode{`typeof(v)==="object" && [<kinds>].indexOf(v[<kind>]) > -1 && v.keys().indexOf(v[<kind>]) > -1`, make(map[string]ConstraintNode, len(a.d))}
+	for k, a := range a.d {
+		c.Children[k] = ConstraintTreeVisited(a, w)
+	}
+	return c
+}
+
+type ObjectValidator map[string]Validator
+
+func Object(d map[string]Validator) Validator {
+	return ObjectValidator(d)
+}
+
+func (d ObjectValidator) Validate(v interface{}, f []string) *Error {
+	return d.ValidateCtx(context.Background(), v, f)
+}
+
+func (d ObjectValidator) ValidateCtx(ctx context.Context, v interface{}, f []string) *Error {
+	o, k := v.(map[string]interface{})
+	if !k {
+		return &Error{"value_must_be_object", f, nil}
+	}
+	ctx, e := descend(ctx, f)
+	if e != nil {
+		return e
+	}
+	ae := make([]*Error, 0, len(d))
+	var ks *ObjectKeys
+	for k, _ := range o {
+		if _, ok := d[k]; !ok {
+			ks = d.keys(ks, o)
+			ae = append(ae, &Error{"unexpected_object_key", f, k})
+		}
+	}
+	p := appendField(f, "")
+	for k, a := range d {
+		if tooManyErrors(ae) {
+			break
+		}
+		if ctx.Err() != nil {
+			return &Error{"validation_cancelled", f, ctx.Err().Error()}
+		}
+		u, x := o[k]
+		if !x {
+			if !isOptional(a) {
+				ks = d.keys(ks, o)
+				ae = append(ae, &Error{"missing_object_key", f, k})
+			}
+			continue
+		}
+		p[len(f)] = k
+		if e := ValidateCtx(ctx, a, u, p); e != nil {
+			ae = append(ae, e)
+			p = appendField(f, "")
+		}
+	}
+	if ks != nil && !tooManyErrors(ae) {
+		ae = append(ae, &Error{"object_keys_mismatch", f, *ks})
+	}
+	if tooManyErrors(ae) {
+		ae = ae[:MaxErrors]
+	}
+	if len(ae) == 0 {
+		return NoError
+	}
+	return &Error{"and", []string{}, ae}
+}
+
+// the context of the "object_keys_mismatch" error an Object adds next to
+// its "missing_object_key" and "unexpected_object_key" errors: all keys it
+// expects and all the value has, both sorted, so clients can show them side
+// by side. it's among the Children of the Object's "and" error, not among
+// its Leaves
+type ObjectKeys struct {
+	Expected []string `json:"expected"`
+	Received []string `json:"received"`
+}
+
+// collects the key sets once, on the first key error
+func (d ObjectValidator) keys(ks *ObjectKeys, o map[string]interface{}) *ObjectKeys {
+	if ks != nil {
+		return ks
+	}
+	ks = &ObjectKeys{make([]string, 0, len(d)), make([]string, 0, len(o))}
+	for k, _ := range d {
+		ks.Expected = append(ks.Expected, k)
+	}
+	for k, _ := range o {
+		ks.Received = append(ks.Received, k)
+	}
+	sort.Strings(ks.Expected)
+	sort.Strings(ks.Received)
+	return ks
+}
+
+func (a ObjectValidator) Structure() map[string]Validator {
+	return (map[string]Validator)(a)
+}
+
+// only descends into keys present in both the schema and the value
+func (a ObjectValidator) Traverse(v interface{}, f func(interface{}, Validator)) {
+	o, _ := v.(map[string]interface{})
+	for k, b := range a {
+		if u, x := o[k]; x {
+			b.Traverse(u, f)
+		}
+	}
+}
+
+func (a ObjectValidator) TraversePath(v interface{}, p []string, f func(interface{}, []string, Validator)) {
+	o, _ := v.(map[string]interface{})
+	for k, b := range a {
+		if u, x := o[k]; x {
+			TraversePath(b, u, appendField(p, k), f)
+		}
+	}
+}
+
+func (a ObjectValidator) Walk(f func(Validator)) {
+	a.WalkVisited(f, Visited{})
+}
+
+func (a ObjectValidator) WalkVisited(f func(Validator), w Visited) {
+	f(a)
+	for _, b := range a {
+		WalkVisited(b, f, w)
+	}
+}
+
+func (a ObjectValidator) ConstraintTree() ConstraintNode {
+	return a.ConstraintTreeVisited(Visited{})
+}
+
+func (a ObjectValidator) ConstraintTreeVisited(w Visited) ConstraintNode {
+	c := ConstraintNode{`typeof(v)==="object" && v.keys()===[<keys>]`, make(map[string]ConstraintNode, len(a))}
+	for k, a := range a {
+		c.Children[k] = ConstraintTreeVisited(a, w)
+	}
+	return c
+}
+
+// Partial rewrites v for PATCH bodies: every key of every Object in it
+// becomes Optional, so only the keys that are present get validated.
+// unexpected keys are still rejected. it descends through the validators
+// Clone copies, but not into Array or Map elements, which are replaced as a
+// whole by a patch. Lazies and Refs are made partial when first used
+func Partial(v Validator) Validator {
+	return partial(v, make(map[Validator]Validator))
+}
+
+// m maps recursive validators to their partial copies
+func partial(v Validator, m map[Validator]Validator) Validator {
+	switch a := v.(type) {
+	case ObjectValidator:
+		o := make(ObjectValidator, len(a))
+		for k, b := range a {
+			if isOptional(b) {
+				o[k] = partial(b, m)
+			} else {
+				o[k] = Optional(partial(b, m))
+			}
+		}
+		return o
+	case AndValidator:
+		o := make(AndValidator, len(a))
+		for i, b := range a {
+			o[i] = partial(b, m)
+		}
+		return o
+	case OrValidator:
+		o := make(OrValidator, len(a))
+		for i, b := range a {
+			o[i] = partial(b, m)
+		}
+		return o
+	case BestMatchOrValidator:
+		o := make(BestMatchOrValidator, len(a))
+		for i, b := range a {
+			o[i] = partial(b, m)
+		}
+		return o
+	case NullableValidator:
+		return NullableValidator{partial(a.e, m)}
+	case OptionalValidator:
+		return OptionalValidator{partial(a.e, m)}
+	case AnnotatedValidator:
+		return AnnotatedValidator{partial(a.e, m), a.m}
+	case CaseValidator:
+		return CaseValidator(partialMap(a, m))
+	case NestedMatchesKindValidator:
+		return NestedMatchesKindValidator{a.k, partialMap(a.d, m)}
+	case DiscriminatorValidator:
+		return DiscriminatorValidator{a.k, partialMap(a.d, m)}
+	case WhenValidator:
+		return WhenValidator{a.p, partial(a.t, m)}
+	case PrefixFieldsValidator:
+		return PrefixFieldsValidator{a.b, partial(a.e, m)}
+	case RecoverValidator:
+		return RecoverValidator{partial(a.e, m)}
+	case TeeValidator:
+		return TeeValidator{partial(a.e, m), a.h}
+	case TimeBudgetValidator:
+		return TimeBudgetValidator{a.d, partial(a.e, m)}
+	case MaxDepthValidator:
+		return MaxDepthValidator{partial(a.e, m), a.n}
+	case *MemoizedValidator:
+		return MemoizeN(partial(a.e, m), a.n)
+	case *RecursiveValidator:
+		if r, k := m[a]; k {
+			return r
+		}
+		r := &RecursiveValidator{}
+		m[a] = r
+		r.Define(partial(a.v, m))
+		return r
+	case *LazyValidator:
+		if r, k := m[a]; k {
+			return r
+		}
+		r := &LazyValidator{}
+		m[a] = r
+		r.f = func() Validator {
+			return partial(a.Validator(), map[Validator]Validator{a: r})
+		}
+		return r
+	case RefValidator:
+		if r, k := m[a]; k {
+			return r
+		}
+		r := &LazyValidator{}
+		m[a] = r
+		r.f = func() Validator {
+			if u, k := a.r.Lookup(a.n); k {
+				return partial(u, map[Validator]Validator{a: r})
+			}
+			return a
+		}
+		return r
+	}
+	return v
+}
+
+func partialMap(d map[string]Validator, m map[Validator]Validator) map[string]Validator {
+	o := make(map[string]Validator, len(d))
+	for k, b := range d {
+		o[k] = partial(b, m)
+	}
+	return o
+}
+
+// every element of the array at key k must also appear in the array at key
+// r of the same object. an absent k is left to the object's own validator
+type ElementsFromFieldValidator struct {
+	k, r string
+}
+
+func ElementsFromField(k, r string) Validator {
+	return ElementsFromFieldValidator{k, r}
+}
+
+func (a ElementsFromFieldValidator) Validate(v interface{}, f []string) *Error {
+	o, k := v.(map[string]interface{})
+	if !k {
+		return &Error{"value_must_be_object", f, nil}
+	}
+	u, x := o[a.k]
+	if !x {
+		return NoError
+	}
+	s, k := u.([]interface{})
+	if !k {
+		return &Error{"value_must_be_array", append(f, a.k), nil}
+	}
+	r, k := o[a.r].([]interface{})
+	if !k {
+		return &Error{"value_must_be_array", append(f, a.r), nil}
+	}
+	ae := make([]*Error, 0, 8)
+outer:
+	for i, e := range s {
+		for _, q := range r {
+			if reflect.DeepEqual(e, q) {
+				continue outer
+			}
+		}
+		ae = append(ae, &Error{"element_not_in_allowed_set", append(f, a.k, strconv.Itoa(i)), e})
+	}
+	if len(ae) == 0 {
+		return NoError
+	}
+	return &Error{"and", []string{}, ae}
+}
+
+func (a ElementsFromFieldValidator) Keys() (k, r string) {
+	return a.k, a.r
+}
+
+func (a ElementsFromFieldValidator) Traverse(v interface{}, f func(interface{}, Validator)) {
+	f(v, a)
+}
+
+func (a ElementsFromFieldValidator) Walk(f func(Validator)) {
+	f(a)
+}
+
+func (a ElementsFromFieldValidator) ConstraintTree() ConstraintNode {
+	return ConstraintNode{`typeof(v)==="object" && v[<key>].every(function(e){ return v[<allowed>].indexOf(e) > -1 })`, nil}
+}
+
+// the nested arrays at key d must have exactly the dimensions listed in the
+// whole number array at key s, e.g. {"shape":[2,3],"data":[[1,2,3],[4,5,6]]}
+type MatchesShapeValidator struct {
+	s, d string
+}
+
+func MatchesShape(s, d string) Validator {
+	return MatchesShapeValidator{s, d}
+}
+
+func (a MatchesShapeValidator) Validate(v interface{}, f []string) *Error {
+	o, k := v.(map[string]interface{})
+	if !k {
+		return &Error{"value_must_be_object", f, nil}
+	}
+	if e := Array(And(WholeNumber(), NumberBetween(0, math.MaxFloat64))).Validate(o[a.s], append(f, a.s)); e != nil {
+		return e
+	}
+	ss := o[a.s].([]interface{})
+	sh := make([]int, len(ss))
+	for i, n := range ss {
+		sh[i] = int(n.(float64))
+	}
+	return matchShape(o[a.d], sh, 0, append(f, a.d))
+}
+
+func matchShape(v interface{}, sh []int, d int, f []string) *Error {
+	if d == len(sh) {
+		return NoError
+	}
+	o, k := v.([]interface{})
+	if !k || len(o) != sh[d] {
+		l := -1
+		if k {
+			l = len(o)
+		}
+		return &Error{"data_shape_mismatch", f, map[string]int{"dimension": d, "expected": sh[d], "actual": l}}
+	}
+	for i, u := range o {
+		if e := matchShape(u, sh, d+1, append(f, strconv.Itoa(i))); e != nil {
+			return e
+		}
+	}
+	return NoError
+}
+
+func (a MatchesShapeValidator) Keys() (s, d string) {
+	return a.s, a.d
+}
+
+func (a MatchesShapeValidator) Traverse(v interface{}, f func(interface{}, Validator)) {
+	f(v, a)
+}
+
+func (a MatchesShapeValidator) Walk(f func(Validator)) {
+	f(a)
+}
+
+func (a MatchesShapeValidator) ConstraintTree() ConstraintNode {
+	return ConstraintNode{`typeof(v)==="object" && shapeOf(v[<data>]) === v[<shape>]`, nil}
+}
+
+// validates the value found by navigating p, objects by key and arrays by
+// index, with e. if e is Optional, a path leading nowhere passes
+type AtPathValidator struct {
+	p []string
+	e Validator
+}
+
+func AtPath(p []string, e Validator) Validator {
+	return AtPathValidator{p, e}
+}
+
+func (a AtPathValidator) Validate(v interface{}, f []string) *Error {
+	return a.ValidateCtx(context.Background(), v, f)
+}
+
+func (a AtPathValidator) ValidateCtx(ctx context.Context, v interface{}, f []string) *Error {
+	u, k := lookup(v, a.p)
+	if !k {
+		if isOptional(a.e) {
+			return NoError
+		}
+		return &Error{"path_not_found", append(f, a.p...), nil}
+	}
+	return ValidateCtx(ctx, a.e, u, append(f, a.p...))
+}
+
+func (a AtPathValidator) Path() []string {
+	return a.p
+}
+
+func (a AtPathValidator) Validator() Validator {
+	return a.e
+}
+
+func (a AtPathValidator) Traverse(v interface{}, f func(interface{}, Validator)) {
+	if u, k := lookup(v, a.p); k {
+		a.e.Traverse(u, f)
+	}
+}
+
+func (a AtPathValidator) TraversePath(v interface{}, p []string, f func(interface{}, []string, Validator)) {
+	if u, k := lookup(v, a.p); k {
+		TraversePath(a.e, u, appendField(p, a.p...), f)
+	}
+}
+
+func (a AtPathValidator) Walk(f func(Validator)) {
+	a.WalkVisited(f, Visited{})
+}
+
+func (a AtPathValidator) WalkVisited(f func(Validator), w Visited) {
+	f(a)
+	WalkVisited(a.e, f, w)
+}
+
+func (a AtPathValidator) ConstraintTree() ConstraintNode {
+	return a.ConstraintTreeVisited(Visited{})
+}
+
+func (a AtPathValidator) ConstraintTreeVisited(w Visited) ConstraintNode {
+	c := ConstraintTreeVisited(a.e, w)
+	for i := len(a.p) - 1; i >= 0; i-- {
+		c = ConstraintNode{`true`, map[string]ConstraintNode{a.p[i]: c}}
+	}
+	return c
+}
+
+// the values at paths x and y, as navigated by AtPath, must be deeply equal
+type FieldsEqualValidator struct {
+	x, y []string
+}
+
+func FieldsEqual(x, y []string) Validator {
+	return FieldsEqualValidator{x, y}
+}
+
+func (a FieldsEqualValidator) Validate(v interface{}, f []string) *Error {
+	p, k := lookup(v, a.x)
+	if !k {
+		return &Error{"path_not_found", append(f, a.x...), nil}
+	}
+	q, k := lookup(v, a.y)
+	if !k {
+		return &Error{"path_not_found", append(f, a.y...), nil}
+	}
+	if !reflect.DeepEqual(p, q) {
+		return &Error{"fields_must_be_equal", append(f, a.x...), map[string]interface{}{"other": a.y}}
+	}
+	return NoError
+}
+
+func (a FieldsEqualValidator) Paths() (x, y []string) {
+	return a.x, a.y
+}
+
+func (a FieldsEqualValidator) Traverse(v interface{}, f func(interface{}, Validator)) {
+	f(v, a)
+}
+
+func (a FieldsEqualValidator) Walk(f func(Validator)) {
+	f(a)
+}
+
+func (a FieldsEqualValidator) ConstraintTree() ConstraintNode {
+	return ConstraintNode{`deepEqual(resolve(v, <x>), resolve(v, <y>))`, nil}
+}
+
+type AtMostNKeysValidator struct {
+	n int
+	k []string
+}
+
+func AtMostNKeys(n int, k ...string) Validator {
+	if n < 0 {
+		panic("AtMostNKeys: n < 0")
+	}
+	return AtMostNKeysValidator{n, k}
+}
+
+func (a AtMostNKeysValidator) Validate(v interface{}, f []string) *Error {
+	o, k := v.(map[string]interface{})
+	if !k {
+		return &Error{"value_must_be_object", f, nil}
+	}
+	p := presentKeys(o, a.k)
+	if len(p) > a.n {
+		return &Error{"too_many_of_group", f, map[string]interface{}{"max": a.n, "keys": a.k, "present": p}}
+	}
+	return NoError
+}
+
+func (a AtMostNKeysValidator) Max() int {
+	return a.n
+}
+
+func (a AtMostNKeysValidator) Keys() []string {
+	return a.k
+}
+
+func (a AtMostNKeysValidator) Traverse(v interface{}, f func(interface{}, Validator)) {
+	f(v, a)
+}
+
+func (a AtMostNKeysValidator) Walk(f func(Validator)) {
+	f(a)
+}
+
+func (a AtMostNKeysValidator) ConstraintTree() ConstraintNode {
+	return ConstraintNode{`typeof(v)==="object" && [<keys>].filter(function(k){ return k in v }).length <= n`, nil}
+}
+
+// the number of keys of an object must be between x and y. a y of -1
+// leaves it unbounded
+type KeyCountBetweenValidator struct {
+	x, y int
+}
+
+func KeyCountBetween(x, y int) Validator {
+	if x < 0 || y < x {
+		panic("KeyCountBetween: x < 0 or y < x")
+	}
+	return KeyCountBetweenValidator{x, y}
+}
+
+func MinProperties(x int) Validator {
+	if x < 0 {
+		panic("MinProperties: x < 0")
+	}
+	return KeyCountBetweenValidator{x, -1}
+}
+
+func MaxProperties(y int) Validator {
+	return KeyCountBetween(0, y)
+}
+
+func (a KeyCountBetweenValidator) Validate(v interface{}, f []string) *Error {
+	o, k := v.(map[string]interface{})
+	if !k {
+		return &Error{"value_must_be_object", f, nil}
+	}
+	if len(o) < a.x || (a.y != -1 && len(o) > a.y) {
+		c := map[string]int{"min": a.x}
+		if a.y != -1 {
+			c["max"] = a.y
+		}
+		return &Error{"object_must_have_key_count_between", f, c}
+	}
+	return NoError
+}
+
+func (a KeyCountBetweenValidator) Min() int {
+	return a.x
+}
+
+func (a KeyCountBetweenValidator) Max() int {
+	return a.y
+}
+
+func (a KeyCountBetweenValidator) Traverse(v interface{}, f func(interface{}, Validator)) {
+	f(v, a)
+}
+
+func (a KeyCountBetweenValidator) Walk(f func(Validator)) {
+	f(a)
+}
+
+func (a KeyCountBetweenValidator) ConstraintTree() ConstraintNode {
+	if a.y == -1 {
+		return ConstraintNode{`typeof(v)==="object" && v.keys().length >= min`, nil}
+	}
+	return ConstraintNode{`typeof(v)==="object" && v.keys().length >= min && v.keys().length <= max`, nil}
+}
+
+// between x and y of the keys k must be present. l names the error
+type OneOfKeysValidator struct {
+	x, y int
+	k    []string
+	l    string
+}
+
+func ExactlyOneOfKeys(k ...string) Validator {
+	return OneOfKeysValidator{1, 1, k, "exactly_one_key_required"}
+}
+
+func AtLeastOneOfKeys(k ...string) Validator {
+	return OneOfKeysValidator{1, len(k), k, "at_least_one_key_required"}
+}
+
+func AtMostOneOfKeys(k ...string) Validator {
+	return OneOfKeysValidator{0, 1, k, "at_most_one_key_required"}
+}
+
+func (a OneOfKeysValidator) Validate(v interface{}, f []string) *Error {
+	o, k := v.(map[string]interface{})
+	if !k {
+		return &Error{"value_must_be_object", f, nil}
+	}
+	p := presentKeys(o, a.k)
+	if len(p) < a.x || len(p) > a.y {
+		return &Error{a.l, f, map[string]interface{}{"keys": a.k, "present": p}}
+	}
+	return NoError
+}
+
+func (a OneOfKeysValidator) Min() int {
+	return a.x
+}
+
+func (a OneOfKeysValidator) Max() int {
+	return a.y
+}
+
+func (a OneOfKeysValidator) Keys() []string {
+	return a.k
+}
+
+func (a OneOfKeysValidator) Traverse(v interface{}, f func(interface{}, Validator)) {
+	f(v, a)
+}
+
+func (a OneOfKeysValidator) Walk(f func(Validator)) {
+	f(a)
+}
+
+func (a OneOfKeysValidator) ConstraintTree() ConstraintNode {
+	return ConstraintNode{`typeof(v)==="object" && [<keys>].filter(function(k){ return k in v }).length >= x && [<keys>].filter(function(k){ return k in v }).length <= y`, nil}
+}
+
+// like JSON Schema's dependentRequired: if key i is present, so must be all
+// of the keys t
+type RequiresKeysValidator struct {
+	i string
+	t []string
+}
+
+func RequiresKeys(i string, t ...string) Validator {
+	return RequiresKeysValidator{i, t}
+}
+
+func (a RequiresKeysValidator) Validate(v interface{}, f []string) *Error {
+	o, k := v.(map[string]interface{})
+	if !k {
+		return &Error{"value_must_be_object", f, nil}
+	}
+	if _, x := o[a.i]; !x {
+		return NoError
+	}
+	m := make([]string, 0, len(a.t))
+	for _, k := range a.t {
+		if _, x := o[k]; !x {
+			m = append(m, k)
+		}
+	}
+	if len(m) > 0 {
+		return &Error{"dependent_keys_required", f, map[string]interface{}{"key": a.i, "missing": m}}
+	}
+	return NoError
+}
+
+func (a RequiresKeysValidator) Key() string {
+	return a.i
+}
+
+func (a RequiresKeysValidator) Keys() []string {
+	return a.t
+}
+
+func (a RequiresKeysValidator) Traverse(v interface{}, f func(interface{}, Validator)) {
+	f(v, a)
+}
+
+func (a RequiresKeysValidator) Walk(f func(Validator)) {
+	f(a)
+}
+
+func (a RequiresKeysValidator) ConstraintTree() ConstraintNode {
+	return ConstraintNode{`typeof(v)==="object" && (!(<key> in v) || [<keys>].every(function(k){ return k in v }))`, nil}
+}
+
+type SlugIDValidator struct {
+	i, s string
+	p    func(id, slug string) bool
+}
+
+// the slug must be the id or end in "-" followed by the id, like "my-post-123"
+func SlugContainsID(i, s string) Validator {
+	return SlugIDValidator{i, s, func(id, slug string) bool {
+		return slug == id || strings.HasSuffix(slug, "-"+id)
+	}}
+}
+
+func SlugMatchesID(i, s string, p func(id, slug string) bool) Validator {
+	return SlugIDValidator{i, s, p}
+}
+
+func (a SlugIDValidator) Validate(v interface{}, f []string) *Error {
+	o, k := v.(map[string]interface{})
+	if !k {
+		return &Error{"value_must_be_object", f, nil}
+	}
+	ss := [2]string{}
+	for j, n := range [2]string{a.i, a.s} {
+		u, x := o[n]
+		if !x {
+			return &Error{"missing_object_key", f, n}
+		}
+		if ss[j], k = u.(string); !k {
+			return &Error{"value_must_be_string", append(f, n), nil}
+		}
+	}
+	if !a.p(ss[0], ss[1]) {
+		return &Error{"slug_id_mismatch", append(f, a.s), map[string]string{"id": ss[0], "slug": ss[1]}}
+	}
+	return NoError
+}
+
+func (a SlugIDValidator) Keys() (i, s string) {
+	return a.i, a.s
+}
+
+func (a SlugIDValidator) Traverse(v interface{}, f func(interface{}, Validator)) {
+	f(v, a)
+}
+
+func (a SlugIDValidator) Walk(f func(Validator)) {
+	f(a)
+}
+
+func (a SlugIDValidator) ConstraintTree() ConstraintNode {
+	return ConstraintNode{`typeof(v)==="object" && predicate(v[<id>], v[<slug>])`, nil}
+}
+
+type MapValidator struct {
+	e Validator
+}
+
+func Map(e Validator) Validator {
+	return MapValidator{e}
+}
+
+func (a MapValidator) Validate(v interface{}, f []string) *Error {
+	return a.ValidateCtx(context.Background(), v, f)
+}
+
+func (a MapValidator) ValidateCtx(ctx context.Context, v interface{}, f []string) *Error {
+	o, k := v.(map[string]interface{})
+	if !k {
+		return &Error{"value_must_be_object", f, nil}
+	}
+	ctx, e := descend(ctx, f)
+	if e != nil {
+		return e
+	}
+	ae := make([]*Error, 0, 8)
+	p := appendField(f, "")
+	for k, u := range o {
+		if ctx.Err() != nil {
+			return &Error{"validation_cancelled", f, ctx.Err().Error()}
+		}
+		p[len(f)] = k
+		if e := ValidateCtx(ctx, a.e, u, p); e != nil {
+			ae = append(ae, e)
+			p = appendField(f, "")
+		}
+		if tooManyErrors(ae) {
+			break
+		}
+	}
+	if len(ae) == 0 {
+		return NoError
+	}
+	return &Error{"and", []string{}, ae}
+}
+func (a MapValidator) Validator() Validator {
+	return a.e
+}
+
+func (a MapValidator) Traverse(v interface{}, f func(interface{}, Validator)) {
+	for _, v := range v.(map[string]interface{}) {
+		a.e.Traverse(v, f)
+	}
+}
+
+func (a MapValidator) TraversePath(v interface{}, p []string, f func(interface{}, []string, Validator)) {
+	o, _ := v.(map[string]interface{})
+	for k, u := range o {
+		TraversePath(a.e, u, appendField(p, k), f)
+	}
+}
+
+func (a MapValidator) Walk(f func(Validator)) {
+	a.WalkVisited(f, Visited{})
+}
+
+func (a MapValidator) WalkVisited(f func(Validator), w Visited) {
+	f(a)
+	WalkVisited(a.e, f, w)
+}
+
+func (a MapValidator) ConstraintTree() ConstraintNode {
+	return a.ConstraintTreeVisited(Visited{})
+}
+
+func (a MapValidator) ConstraintTreeVisited(w Visited) ConstraintNode {
+	c := ConstraintNode{`typeof(v)==="object"`, make(map[string]ConstraintNode, 8)}
+	c.Children["*"] = ConstraintTreeVisited(a.e, w)
+	return c
+}
+
+// a Map whose keys must be among k, e.g. histograms like {"red":3,"blue":5}
+type EnumCountMapValidator struct {
+	k []string
+	e Validator
+}
+
+func EnumCountMap(k []string, e Validator) Validator {
+	return EnumCountMapValidator{k, e}
+}
+
+func (a EnumCountMapValidator) Validate(v interface{}, f []string) *Error {
+	return a.ValidateCtx(context.Background(), v, f)
+}
+
+func (a EnumCountMapValidator) ValidateCtx(ctx context.Context, v interface{}, f []string) *Error {
+	o, k := v.(map[string]interface{})
+	if !k {
+		return &Error{"value_must_be_object", f, nil}
+	}
+	ctx, e := descend(ctx, f)
+	if e != nil {
+		return e
+	}
+	ae := make([]*Error, 0, 8)
+outer:
+	for k, u := range o {
+		for _, c := range a.k {
+			if c == k {
+				if e := ValidateCtx(ctx, a.e, u, append(f, k)); e != nil {
+					ae = append(ae, e)
+				}
+				continue outer
+			}
+		}
+		ae = append(ae, &Error{"unexpected_object_key", f, k})
+	}
+	if len(ae) == 0 {
+		return NoError
+	}
+	return &Error{"and", []string{}, ae}
+}
+
+func (a EnumCountMapValidator) Keys() []string {
+	return a.k
+}
+
+func (a EnumCountMapValidator) Validator() Validator {
+	return a.e
+}
+
+func (a EnumCountMapValidator) Traverse(v interface{}, f func(interface{}, Validator)) {
+	o, _ := v.(map[string]interface{})
+	for _, k := range a.k {
+		if u, x := o[k]; x {
+			a.e.Traverse(u, f)
+		}
+	}
+}
+
+func (a EnumCountMapValidator) TraversePath(v interface{}, p []string, f func(interface{}, []string, Validator)) {
+	o, _ := v.(map[string]interface{})
+	for _, k := range a.k {
+		if u, x := o[k]; x {
+			TraversePath(a.e, u, appendField(p, k), f)
+		}
+	}
+}
+
+func (a EnumCountMapValidator) Walk(f func(Validator)) {
+	a.WalkVisited(f, Visited{})
+}
+
+func (a EnumCountMapValidator) WalkVisited(f func(Validator), w Visited) {
+	f(a)
+	WalkVisited(a.e, f, w)
+}
+
+func (a EnumCountMapValidator) ConstraintTree() ConstraintNode {
+	return a.ConstraintTreeVisited(Visited{})
+}
+
+func (a EnumCountMapValidator) ConstraintTreeVisited(w Visited) ConstraintNode {
+	c := ConstraintNode{`typeof(v)==="object" && v.keys().every(function(k){ return [<keys>].indexOf(k) > -1 })`, make(map[string]ConstraintNode, 8)}
+	c.Children["*"] = ConstraintTreeVisited(a.e, w)
+	return c
+}
+
+// counts the bytes of every key and string value; other values count with
+// the size of their JSON encoding
+type MetadataSizeLimitValidator struct {
+	n int
+}
+
+func MetadataSizeLimit(n int) Validator {
+	return MetadataSizeLimitValidator{n}
+}
+
+func (a MetadataSizeLimitValidator) Validate(v interface{}, f []string) *Error {
+	o, k := v.(map[string]interface{})
+	if !k {
+		return &Error{"value_must_be_object", f, nil}
+	}
+	n := 0
+	for k, u := range o {
+		n += len(k)
+		if s, x := u.(string); x {
+			n += len(s)
+		} else if b, err := json.Marshal(u); err == nil {
+			n += len(b)
+		}
+	}
+	if n > a.n {
+		return &Error{"metadata_too_large", f, map[string]int{"max": a.n, "size": n}}
+	}
+	return NoError
+}
+
+func (a MetadataSizeLimitValidator) Max() int {
+	return a.n
+}
+
+func (a MetadataSizeLimitValidator) Traverse(v interface{}, f func(interface{}, Validator)) {
+	f(v, a)
+}
+
+func (a MetadataSizeLimitValidator) Walk(f func(Validator)) {
+	f(a)
+}
+
+func (a MetadataSizeLimitValidator) ConstraintTree() ConstraintNode {
+	return ConstraintNode{`typeof(v)==="object" && metadataSize(v) <= max`, nil}
+}
+
+type ArrayValidator struct {
+	e Validator
+}
+
+func Array(e Validator) Validator {
+	return ArrayValidator{e}
+}
+
+func (a ArrayValidator) Validate(v interface{}, f []string) *Error {
+	return a.ValidateCtx(context.Background(), v, f)
+}
+
+func (a ArrayValidator) ValidateCtx(ctx context.Context, v interface{}, f []string) *Error {
+	o, k := v.([]interface{})
+	if !k {
+		return &Error{"value_must_be_array", f, nil}
+	}
+	ctx, e := descend(ctx, f)
+	if e != nil {
+		return e
+	}
+	ae := make([]*Error, 0, 8)
+	p := appendField(f, "")
+	for i, u := range o {
+		if ctx.Err() != nil {
+			return &Error{"validation_cancelled", f, ctx.Err().Error()}
+		}
+		p[len(f)] = strconv.Itoa(i)
+		if e := ValidateCtx(ctx, a.e, u, p); e != nil {
+			ae = append(ae, &Error{"array_item", p, e})
+			p = appendField(f, "")
+		}
+		if tooManyErrors(ae) {
+			break
+		}
+	}
+	if len(ae) == 0 {
+		return NoError
+	}
+	return &Error{"and", []string{}, ae}
+}
+func (a ArrayValidator) Validator() Validator {
+	return a.e
+}
+
+func (a ArrayValidator) Traverse(v interface{}, f func(interface{}, Validator)) {
+	for _, v := range v.([]interface{}) {
+		a.e.Traverse(v, f)
+	}
+}
+
+func (a ArrayValidator) TraversePath(v interface{}, p []string, f func(interface{}, []string, Validator)) {
+	o, _ := v.([]interface{})
+	for i, u := range o {
+		TraversePath(a.e, u, appendField(p, strconv.Itoa(i)), f)
+	}
+}
+
+func (a ArrayValidator) Walk(f func(Validator)) {
+	a.WalkVisited(f, Visited{})
+}
+
+func (a ArrayValidator) WalkVisited(f func(Validator), w Visited) {
+	f(a)
+	WalkVisited(a.e, f, w)
+}
+
+func (a ArrayValidator) ConstraintTree() ConstraintNode {
+	return a.ConstraintTreeVisited(Visited{})
+}
+
+func (a ArrayValidator) ConstraintTreeVisited(w Visited) ConstraintNode {
+	c := ConstraintNode{`typeof(v)==="array"`, make(map[string]ConstraintNode, 8)}
+	c.Children["*"] = ConstraintTreeVisited(a.e, w)
+	return c
+}
+
+// counts the elements of an array that pass e. AtLeast wants n or more of
+// them and fails with "too_few_matching_items", ExactlyN wants n and fails
+// with "matching_item_count_mismatch"; the context holds the required and
+// the actual count
+type MatchCountValidator struct {
+	n int
+	x bool
+	e Validator
+}
+
+func AtLeast(n int, e Validator) Validator {
+	if n < 0 {
+		panic("AtLeast: n < 0")
+	}
+	return MatchCountValidator{n, false, e}
+}
+
+func ExactlyN(n int, e Validator) Validator {
+	if n < 0 {
+		panic("ExactlyN: n < 0")
+	}
+	return MatchCountValidator{n, true, e}
+}
+
+func (a MatchCountValidator) Count() int {
+	return a.n
+}
+
+func (a MatchCountValidator) Exact() bool {
+	return a.x
+}
+
+func (a MatchCountValidator) Validator() Validator {
+	return a.e
+}
+
+func (a MatchCountValidator) Validate(v interface{}, f []string) *Error {
+	return a.ValidateCtx(context.Background(), v, f)
+}
+
+func (a MatchCountValidator) ValidateCtx(ctx context.Context, v interface{}, f []string) *Error {
+	o, k := v.([]interface{})
+	if !k {
+		return &Error{"value_must_be_array", f, nil}
+	}
+	ctx, e := descend(ctx, f)
+	if e != nil {
+		return e
+	}
+	c := 0
+	p := appendField(f, "")
+	for i, u := range o {
+		if ctx.Err() != nil {
+			return &Error{"validation_cancelled", f, ctx.Err().Error()}
+		}
+		p[len(f)] = strconv.Itoa(i)
+		if ValidateCtx(ctx, a.e, u, p) == nil {
+			c++
+		}
+	}
+	switch {
+	case c < a.n:
+		if a.x {
+			return &Error{"matching_item_count_mismatch", f, map[string]int{"required": a.n, "actual": c}}
+		}
+		return &Error{"too_few_matching_items", f, map[string]int{"required": a.n, "actual": c}}
+	case c > a.n && a.x:
+		return &Error{"matching_item_count_mismatch", f, map[string]int{"required": a.n, "actual": c}}
+	}
+	return NoError
+}
+
+func (a MatchCountValidator) Traverse(v interface{}, f func(interface{}, Validator)) {
+	for _, v := range v.([]interface{}) {
+		a.e.Traverse(v, f)
+	}
+}
+
+func (a MatchCountValidator) TraversePath(v interface{}, p []string, f func(interface{}, []string, Validator)) {
+	o, _ := v.([]interface{})
+	for i, u := range o {
+		TraversePath(a.e, u, appendField(p, strconv.Itoa(i)), f)
+	}
+}
+
+func (a MatchCountValidator) Walk(f func(Validator)) {
+	a.WalkVisited(f, Visited{})
+}
+
+func (a MatchCountValidator) WalkVisited(f func(Validator), w Visited) {
+	f(a)
+	WalkVisited(a.e, f, w)
+}
+
+func (a MatchCountValidator) ConstraintTree() ConstraintNode {
+	return a.ConstraintTreeVisited(Visited{})
+}
+
+func (a MatchCountValidator) ConstraintTreeVisited(w Visited) ConstraintNode {
+	c := ConstraintNode{`typeof(v)==="array" && v.filter(match).length >= n`, make(map[string]ConstraintNode, 1)}
+	if a.x {
+		c.Constraint = `typeof(v)==="array" && v.filter(match).length === n`
+	}
+	c.Children["match"] = ConstraintTreeVisited(a.e, w)
+	return c
+}
+
+// elements are RFC 3339 strings or epoch seconds; consecutive ones must be
+// i seconds apart, give or take t
+type FixedIntervalValidator struct {
+	i, t float64
+}
+
+func FixedInterval(i, t float64) Validator {
+	if i <= 0 || t < 0 {
+		panic("FixedInterval: interval must be positive and tolerance non-negative")
+	}
+	return FixedIntervalValidator{i, t}
+}
+
+func (a FixedIntervalValidator) Validate(v interface{}, f []string) *Error {
+	return And(Array(Anything()), lambda(func(v interface{}, f []string) *Error {
+		p := 0.0
+		for i, u := range v.([]interface{}) {
+			var s float64
+			switch t := u.(type) {
+			case float64:
+				s = t
+			case string:
+				m, err := time.Parse(time.RFC3339Nano, t)
+				if err != nil {
+					return &Error{"value_must_be_timestamp", append(f, strconv.Itoa(i)), nil}
+				}
+				s = float64(m.UnixNano()) / 1e9
+			default:
+				return &Error{"value_must_be_timestamp", append(f, strconv.Itoa(i)), nil}
+			}
+			if i > 0 && math.Abs(s-p-a.i) > a.t {
+				return &Error{"timestamp_interval_irregular", append(f, strconv.Itoa(i)), i}
+			}
+			p = s
+		}
+		return NoError
+	})).Validate(v, f)
+}
+
+func (a FixedIntervalValidator) Interval() float64 {
+	return a.i
+}
+
+func (a FixedIntervalValidator) Tolerance() float64 {
+	return a.t
+}
+
+func (a FixedIntervalValidator) Traverse(v interface{}, f func(interface{}, Validator)) {
+	f(v, a)
+}
+
+func (a FixedIntervalValidator) Walk(f func(Validator)) {
+	f(a)
+}
+
+func (a FixedIntervalValidator) ConstraintTree() ConstraintNode {
+	return ConstraintNode{`typeof(v)==="array" && v.every(function(e, i){ return i === 0 || Math.abs(ts(e) - ts(v[i-1]) - interval) <= tolerance })`, nil}
+}
+
+// the numbers at key k of all objects in the array must add up to s, give
+// or take t
+type WeightsSumToValidator struct {
+	k    string
+	s, t float64
+}
+
+func WeightsSumTo(k string, s, t float64) Validator {
+	return WeightsSumToValidator{k, s, t}
+}
+
+func (a WeightsSumToValidator) Validate(v interface{}, f []string) *Error {
+	return And(Array(Map(Anything())), lambda(func(v interface{}, f []string) *Error {
+		m := 0.0
+		for i, u := range v.([]interface{}) {
+			w, x := u.(map[string]interface{})[a.k]
+			if !x {
+				return &Error{"missing_object_key", append(f, strconv.Itoa(i)), a.k}
+			}
+			n, k := w.(float64)
+			if !k {
+				return &Error{"value_must_be_number", append(f, strconv.Itoa(i), a.k), nil}
+			}
+			m += n
+		}
+		if math.Abs(m-a.s) > a.t {
+			return &Error{"weights_do_not_sum", f, map[string]float64{"sum": m, "target": a.s}}
+		}
+		return NoError
+	})).Validate(v, f)
+}
+
+func (a WeightsSumToValidator) Traverse(v interface{}, f func(interface{}, Validator)) {
+	f(v, a)
+}
+
+func (a WeightsSumToValidator) Walk(f func(Validator)) {
+	f(a)
+}
+
+func (a WeightsSumToValidator) ConstraintTree() ConstraintNode {
+	return ConstraintNode{`typeof(v)==="array" && Math.abs(v.reduce(function(s, e){ return s + e[<key>] }, 0) - target) <= tolerance`, nil}
+}
+
+// arrays of [value, count] pairs with positive whole counts. the strict
+// variant also rejects consecutive pairs with equal values
+type RunLengthEncodingValidator struct {
+	e Validator
+	s bool
+}
+
+func RunLengthEncoding(e Validator) Validator {
+	return RunLengthEncodingValidator{e, false}
+}
+
+func StrictRunLengthEncoding(e Validator) Validator {
+	return RunLengthEncodingValidator{e, true}
+}
+
+func (a RunLengthEncodingValidator) Validate(v interface{}, f []string) *Error {
+	return a.ValidateCtx(context.Background(), v, f)
+}
+
+func (a RunLengthEncodingValidator) ValidateCtx(ctx context.Context, v interface{}, f []string) *Error {
+	o, k := v.([]interface{})
+	if !k {
+		return &Error{"value_must_be_array", f, nil}
+	}
+	ae := make([]*Error, 0, 8)
+	for i, u := range o {
+		p, k := u.([]interface{})
+		if !k || len(p) != 2 {
+			ae = append(ae, &Error{"invalid_rle", append(f, strconv.Itoa(i)), "pair"})
+			continue
+		}
+		if c, k := p[1].(float64); !k || c < 1 || c != math.Trunc(c) {
+			ae = append(ae, &Error{"invalid_rle", append(f, strconv.Itoa(i), "1"), "count"})
+		}
+		if a.s && i > 0 {
+			if q, k := o[i-1].([]interface{}); k && len(q) == 2 && reflect.DeepEqual(q[0], p[0]) {
+				ae = append(ae, &Error{"invalid_rle", append(f, strconv.Itoa(i), "0"), "unmerged"})
+			}
+		}
+		if e := ValidateCtx(ctx, a.e, p[0], append(f, strconv.Itoa(i), "0")); e != nil {
+			ae = append(ae, e)
+		}
+	}
+	if len(ae) == 0 {
+		return NoError
+	}
+	return &Error{"and", []string{}, ae}
+}
+
+func (a RunLengthEncodingValidator) Validator() Validator {
+	return a.e
+}
+
+func (a RunLengthEncodingValidator) Traverse(v interface{}, f func(interface{}, Validator)) {
+	o, _ := v.([]interface{})
+	for _, u := range o {
+		if p, k := u.([]interface{}); k && len(p) == 2 {
+			a.e.Traverse(p[0], f)
+		}
+	}
+}
+
+func (a RunLengthEncodingValidator) TraversePath(v interface{}, p []string, f func(interface{}, []string, Validator)) {
+	o, _ := v.([]interface{})
+	for i, u := range o {
+		if r, k := u.([]interface{}); k && len(r) == 2 {
+			TraversePath(a.e, r[0], appendField(p, strconv.Itoa(i), "0"), f)
+		}
+	}
+}
+
+func (a RunLengthEncodingValidator) Walk(f func(Validator)) {
+	a.WalkVisited(f, Visited{})
+}
+
+func (a RunLengthEncodingValidator) WalkVisited(f func(Validator), w Visited) {
+	f(a)
+	WalkVisited(a.e, f, w)
+}
+
+func (a RunLengthEncodingValidator) ConstraintTree() ConstraintNode {
+	return a.ConstraintTreeVisited(Visited{})
+}
+
+func (a RunLengthEncodingValidator) ConstraintTreeVisited(w Visited) ConstraintNode {
+	c := ConstraintNode{`typeof(v)==="array" && v.every(function(p){ return p.length === 2 && p[1] % 1 === 0 && p[1] > 0 })`, make(map[string]ConstraintNode, 1)}
+	c.Children["*"] = ConstraintTreeVisited(a.e, w)
+	return c
+}
+
+// an array of at least n distinct, non-empty strings. errors about an
+// element are at its index
+type StringSetValidator struct {
+	n int
+}
+
+func StringSet(n int) Validator {
+	if n < 0 {
+		panic("StringSet: n < 0")
+	}
+	return StringSetValidator{n}
+}
+
+func (a StringSetValidator) Min() int {
+	return a.n
+}
+
+func (a StringSetValidator) Validate(v interface{}, f []string) *Error {
+	return And(Array(And(String(), LengthMin(1))), LengthMin(a.n), lambda(func(v interface{}, f []string) *Error {
+		o := v.([]interface{})
+		m := make(map[string]bool, len(o))
+		for i, u := range o {
+			if m[u.(string)] {
+				return &Error{"array_items_must_be_unique", appendField(f, strconv.Itoa(i)), nil}
+			}
+			m[u.(string)] = true
+		}
+		return NoError
+	})).Validate(v, f)
+}
+
+func (a StringSetValidator) Traverse(v interface{}, f func(interface{}, Validator)) {
+	f(v, a)
+}
+
+func (a StringSetValidator) Walk(f func(Validator)) {
+	f(a)
+}
+
+func (a StringSetValidator) ConstraintTree() ConstraintNode {
+	return ConstraintNode{`Array.isArray(v) && v.length >= min && v.every(function(s, i){ return typeof(s)==="string" && s !== "" && v.indexOf(s) === i })`, nil}
+}
+
+// all values of an object, or elements of an array, must be of the same
+// JSON type, whichever that is
+type HomogeneousValidator struct {
+	o bool
+}
+
+func HomogeneousMap() Validator {
+	return HomogeneousValidator{true}
+}
+
+func HomogeneousArray() Validator {
+	return HomogeneousValidator{false}
+}
+
+func (a HomogeneousValidator) Validate(v interface{}, f []string) *Error {
+	var us []interface{}
+	if a.o {
+		o, k := v.(map[string]interface{})
+		if !k {
+			return &Error{"value_must_be_object", f, nil}
+		}
+		for _, u := range o {
+			us = append(us, u)
+		}
+	} else {
+		o, k := v.([]interface{})
+		if !k {
+			return &Error{"value_must_be_array", f, nil}
+		}
+		us = o
+	}
+	m := make(map[string]bool, 1)
+	for _, u := range us {
+		m[jsonType(u)] = true
+	}
+	if len(m) > 1 {
+		ts := make([]string, 0, len(m))
+		for t, _ := range m {
+			ts = append(ts, t)
+		}
+		sort.Strings(ts)
+		return &Error{"values_must_be_homogeneous", f, map[string]interface{}{"types": ts}}
+	}
+	return NoError
+}
+
+func (a HomogeneousValidator) Traverse(v interface{}, f func(interface{}, Validator)) {
+	f(v, a)
+}
+
+func (a HomogeneousValidator) Walk(f func(Validator)) {
+	f(a)
+}
+
+func (a HomogeneousValidator) ConstraintTree() ConstraintNode {
+	if a.o {
+		return ConstraintNode{`typeof(v)==="object" && new Set(Object.values(v).map(jsonType)).size <= 1`, nil}
+	}
+	return ConstraintNode{`Array.isArray(v) && new Set(v.map(jsonType)).size <= 1`, nil}
+}
+
+// the JSON type name of a decoded value
+func jsonType(v interface{}) string {
+	switch v.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case float64, json.Number:
+		return "number"
+	case string:
+		return "string"
+	case []interface{}:
+		return "array"
+	case map[string]interface{}:
+		return "object"
+	}
+	return fmt.Sprintf("%T", v)
+}
+
+// an array without null elements. the error names the first null's index
+type NoNullItemsValidator struct{}
+
+func NoNullItems() Validator {
+	return NoNullItemsValidator{}
+}
+
+func (a NoNullItemsValidator) Validate(v interface{}, f []string) *Error {
+	return And(Array(Anything()), lambda(func(v interface{}, f []string) *Error {
+		for i, u := range v.([]interface{}) {
+			if u == nil {
+				return &Error{"array_must_not_contain_null", f, i}
+			}
+		}
+		return NoError
+	})).Validate(v, f)
+}
+
+func (a NoNullItemsValidator) Traverse(v interface{}, f func(interface{}, Validator)) {
+	f(v, a)
+}
+
+func (a NoNullItemsValidator) Walk(f func(Validator)) {
+	f(a)
+}
+
+func (a NoNullItemsValidator) ConstraintTree() ConstraintNode {
+	return ConstraintNode{`Array.isArray(v) && v.indexOf(null) === -1`, nil}
+}
+
+// c returns a negative number when a sorts before b, zero when they're equal
+// and a positive number otherwise
+type SortedValidator struct {
+	c func(a, b interface{}) int
+	s bool
+}
+
+func Sorted(c func(a, b interface{}) int) Validator {
+	return SortedValidator{c, false}
+}
+
+// rejects equal neighbours too
+func StrictlySorted(c func(a, b interface{}) int) Validator {
+	return SortedValidator{c, true}
+}
+
+func SortedNumbers() Validator {
+	return And(Array(Number()), Sorted(compareNumbers))
+}
+
+func SortedStrings() Validator {
+	return And(Array(String()), Sorted(compareStrings))
+}
+
+func (a SortedValidator) Strict() bool {
+	return a.s
+}
+
+func (a SortedValidator) Validate(v interface{}, f []string) *Error {
+	return And(Array(Anything()), lambda(func(v interface{}, f []string) *Error {
+		o := v.([]interface{})
+		for i := 1; i < len(o); i++ {
+			if c := a.c(o[i-1], o[i]); c > 0 || (a.s && c == 0) {
+				return &Error{"array_must_be_sorted", f, i}
+			}
+		}
+		return NoError
+	})).Validate(v, f)
+}
+
+func (a SortedValidator) Traverse(v interface{}, f func(interface{}, Validator)) {
+	f(v, a)
+}
+
+func (a SortedValidator) Walk(f func(Validator)) {
+	f(a)
+}
+
+func (a SortedValidator) ConstraintTree() ConstraintNode {
+	if a.s {
+		return ConstraintNode{`typeof(v)==="array" && v.every(function(e, i){ return i === 0 || cmp(v[i-1], e) < 0 })`, nil}
+	}
+	return ConstraintNode{`typeof(v)==="array" && v.every(function(e, i){ return i === 0 || cmp(v[i-1], e) <= 0 })`, nil}
+}
+
+func compareNumbers(a, b interface{}) int {
+	x, y := a.(float64), b.(float64)
+	switch {
+	case x < y:
+		return -1
+	case x > y:
+		return 1
+	}
+	return 0
+}
+
+func compareStrings(a, b interface{}) int {
+	return strings.Compare(a.(string), b.(string))
+}
+
+type RegexValidator struct {
+	x, l string
+	i, m bool
+}
+
+// see https://golang.org/pkg/regexp/syntax/. panics if x doesn't compile
+func Regex(x, l string, i, m bool) Validator {
+	a := RegexValidator{x, l, i, m}
+	if _, err := regexp.Compile(a.Normalized()); err != nil {
+		panic("Regex: " + err.Error())
+	}
+	return a
+}
+
+func (a RegexValidator) Label() string {
+	return a.l
+}
+
+func (a RegexValidator) Expression() string {
+	return a.x
+}
+
+func (a RegexValidator) Modifiers() (i, m bool) {
+	return a.i, a.m
+}
+
+// the expression with its modifiers as inline flags, e.g. "(?m)(?i)^a"
+func (a RegexValidator) Normalized() string {
+	x := a.x
+	if a.i {
+		x = `(?i)` + x
+	}
+	if a.m {
+		x = `(?m)` + x
+	}
+	return x
+}
+
+func (a RegexValidator) Regex() *regexp.Regexp {
+	return regexp.MustCompile(a.Normalized())
+}
+
+func (a RegexValidator) Validate(v interface{}, f []string) *Error {
+	return And(String(), lambda(func(v interface{}, f []string) *Error {
+		s := v.(string)
+		if a.Regex().MatchString(s) {
+			return NoError
+		}
+		return &Error{a.l, f, map[string]interface{}{
+			"regex": map[string]interface{}{
+				"expression": a.x,
+				"modifiers": map[string]bool{
+					"i": a.i,
+					"m": a.m,
+				},
+			},
+		}}
+	})).Validate(v, f)
+}
+
+func (a RegexValidator) Traverse(v interface{}, f func(interface{}, Validator)) {
+	f(v, a)
+}
+
+func (a RegexValidator) Walk(f func(Validator)) {
+	f(a)
+}
+
+func (a RegexValidator) ConstraintTree() ConstraintNode {
+	return ConstraintNode{`typeof(v)==="string" && /<regex>/.matches(v)`, nil}
+}
+
+// c is an IANA charset name like "ISO-8859-1" or "latin1"
+type EncodableInValidator struct {
+	c string
+	e encoding.Encoding
+}
+
+func EncodableIn(c string) Validator {
+	e, err := ianaindex.IANA.Encoding(c)
+	if err != nil || e == nil {
+		panic("EncodableIn: unsupported charset " + c)
+	}
+	return EncodableInValidator{c, e}
+}
+
+func (a EncodableInValidator) Charset() string {
+	return a.c
+}
+
+func (a EncodableInValidator) Validate(v interface{}, f []string) *Error {
+	return And(String(), lambda(func(v interface{}, f []string) *Error {
+		n := a.e.NewEncoder()
+		for i, r := range v.(string) {
+			if _, err := n.String(string(r)); err != nil {
+				return &Error{"value_not_encodable_in_charset", f, map[string]interface{}{"charset": a.c, "rune": string(r), "position": i}}
+			}
+		}
+		return NoError
+	})).Validate(v, f)
+}
+
+func (a EncodableInValidator) Traverse(v interface{}, f func(interface{}, Validator)) {
+	f(v, a)
+}
+
+func (a EncodableInValidator) Walk(f func(Validator)) {
+	f(a)
+}
+
+func (a EncodableInValidator) ConstraintTree() ConstraintNode {
+	return ConstraintNode{`typeof(v)==="string" && encodable(v, <charset>)`, nil}
+}
+
+// rejects strings made of nothing but unicode.IsSpace whitespace, the empty
+// string included
+type NotBlankValidator struct{}
+
+func NotBlank() Validator {
+	return NotBlankValidator{}
+}
+
+func (a NotBlankValidator) Validate(v interface{}, f []string) *Error {
+	return And(String(), lambda(func(v interface{}, f []string) *Error {
+		if strings.TrimSpace(v.(string)) == "" {
+			return &Error{"string_must_not_be_blank", f, nil}
+		}
+		return NoError
+	})).Validate(v, f)
+}
+
+func (a NotBlankValidator) Traverse(v interface{}, f func(interface{}, Validator)) {
+	f(v, a)
+}
+
+func (a NotBlankValidator) Walk(f func(Validator)) {
+	f(a)
+}
+
+func (a NotBlankValidator) ConstraintTree() ConstraintNode {
+	return ConstraintNode{`typeof(v)==="string" && v.trim() !== ""`, nil}
+}
+
+// with an empty cutset, anything unicode.IsSpace counts as whitespace
+type TrimmedValidator struct {
+	c string
+}
+
+func Trimmed() Validator {
+	return TrimmedValidator{""}
+}
+
+func TrimmedCutset(c string) Validator {
+	if c == "" {
+		panic("TrimmedCutset: empty cutset")
+	}
+	return TrimmedValidator{c}
+}
+
+func (a TrimmedValidator) Cutset() string {
+	return a.c
+}
+
+func (a TrimmedValidator) Validate(v interface{}, f []string) *Error {
+	return And(String(), lambda(func(v interface{}, f []string) *Error {
+		s, t := v.(string), ""
+		if a.c == "" {
+			t = strings.TrimFunc(s, unicode.IsSpace)
+		} else {
+			t = strings.Trim(s, a.c)
+		}
+		if t != s {
+			return &Error{"string_must_be_trimmed", f, nil}
+		}
+		return NoError
+	})).Validate(v, f)
+}
+
+func (a TrimmedValidator) Traverse(v interface{}, f func(interface{}, Validator)) {
+	f(v, a)
+}
+
+func (a TrimmedValidator) Walk(f func(Validator)) {
+	f(a)
+}
+
+func (a TrimmedValidator) ConstraintTree() ConstraintNode {
+	if a.c == "" {
+		return ConstraintNode{`typeof(v)==="string" && v.trim() === v`, nil}
+	}
+	return ConstraintNode{`typeof(v)==="string" && trim(v, <cutset>) === v`, nil}
+}
+
+// absolute URLs with a host. with schemes given, only those are allowed
+type URLValidator struct {
+	s []string
+}
+
+func URL(s ...string) Validator {
+	return URLValidator{s}
+}
+
+func (a URLValidator) Schemes() []string {
+	return a.s
+}
+
+func (a URLValidator) Validate(v interface{}, f []string) *Error {
+	return And(String(), lambda(func(v interface{}, f []string) *Error {
+		u, err := url.Parse(v.(string))
+		if err != nil || u.Scheme == "" || u.Host == "" {
+			return &Error{"value_must_be_url", f, nil}
+		}
+		if len(a.s) == 0 {
+			return NoError
+		}
+		for _, s := range a.s {
+			if strings.EqualFold(s, u.Scheme) {
+				return NoError
+			}
+		}
+		return &Error{"url_scheme_not_allowed", f, a.s}
+	})).Validate(v, f)
+}
+
+func (a URLValidator) Traverse(v interface{}, f func(interface{}, Validator)) {
+	f(v, a)
+}
+
+func (a URLValidator) Walk(f func(Validator)) {
+	f(a)
+}
+
+func (a URLValidator) ConstraintTree() ConstraintNode {
+	return ConstraintNode{`typeof(v)==="string" && isURL(v) && [<schemes>].indexOf(scheme(v)) > -1`, nil}
+}
+
+// the family follows the notation: "::ffff:1.2.3.4" is an IPv6 address even
+// though it maps an IPv4 one, and only dotted quads are IPv4
+type IPValidator struct {
+	v int
+}
+
+func IP() Validator {
+	return IPValidator{0}
+}
+
+func IPv4() Validator {
+	return IPValidator{4}
+}
+
+func IPv6() Validator {
+	return IPValidator{6}
+}
+
+func (a IPValidator) Version() int {
+	return a.v
+}
+
+func (a IPValidator) Validate(v interface{}, f []string) *Error {
+	return And(String(), lambda(func(v interface{}, f []string) *Error {
+		s := v.(string)
+		l := "value_must_be_ip"
+		if a.v != 0 {
+			l += "v" + strconv.Itoa(a.v)
+		}
+		if net.ParseIP(s) == nil {
+			return &Error{l, f, nil}
+		}
+		if c := strings.Contains(s, ":"); (a.v == 4 && c) || (a.v == 6 && !c) {
+			return &Error{l, f, nil}
+		}
+		return NoError
+	})).Validate(v, f)
+}
+
+func (a IPValidator) Traverse(v interface{}, f func(interface{}, Validator)) {
+	f(v, a)
+}
+
+func (a IPValidator) Walk(f func(Validator)) {
+	f(a)
+}
+
+func (a IPValidator) ConstraintTree() ConstraintNode {
+	switch a.v {
+	case 4:
+		return ConstraintNode{`typeof(v)==="string" && isIPv4(v)`, nil}
+	case 6:
+		return ConstraintNode{`typeof(v)==="string" && isIPv6(v)`, nil}
+	}
+	return ConstraintNode{`typeof(v)==="string" && (isIPv4(v) || isIPv6(v))`, nil}
+}
+
+// RFC 1123 host names. letters may be upper case and punycode labels like
+// "xn--bcher-kva" are just labels. a single trailing dot, as in fully
+// qualified names, is accepted and doesn't count towards the 253 characters
+type HostnameValidator struct{}
+
+func Hostname() Validator {
+	return HostnameValidator{}
+}
+
+func (a HostnameValidator) Validate(v interface{}, f []string) *Error {
+	return And(String(), lambda(func(v interface{}, f []string) *Error {
+		if !isHostname(v.(string)) {
+			return &Error{"value_must_be_hostname", f, nil}
+		}
+		return NoError
+	})).Validate(v, f)
+}
+
+func (a HostnameValidator) Traverse(v interface{}, f func(interface{}, Validator)) {
+	f(v, a)
+}
+
+func (a HostnameValidator) Walk(f func(Validator)) {
+	f(a)
+}
+
+func (a HostnameValidator) ConstraintTree() ConstraintNode {
+	return ConstraintNode{`typeof(v)==="string" && isHostname(v)`, nil}
+}
+
+func isHostname(s string) bool {
+	s = strings.TrimSuffix(s, ".")
+	if len(s) == 0 || len(s) > 253 {
+		return false
+	}
+	for _, l := range strings.Split(s, ".") {
+		if len(l) == 0 || len(l) > 63 || l[0] == '-' || l[len(l)-1] == '-' {
+			return false
+		}
+		for i := 0; i < len(l); i++ {
+			c := l[i]
+			if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '-') {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+type CIDRValidator struct{}
+
+func CIDR() Validator {
+	return CIDRValidator{}
+}
+
+func (a CIDRValidator) Validate(v interface{}, f []string) *Error {
+	return And(String(), lambda(func(v interface{}, f []string) *Error {
+		if _, _, err := net.ParseCIDR(v.(string)); err != nil {
+			return &Error{"value_must_be_cidr", f, nil}
+		}
+		return NoError
+	})).Validate(v, f)
+}
+
+func (a CIDRValidator) Traverse(v interface{}, f func(interface{}, Validator)) {
+	f(v, a)
+}
+
+func (a CIDRValidator) Walk(f func(Validator)) {
+	f(a)
+}
+
+func (a CIDRValidator) ConstraintTree() ConstraintNode {
+	return ConstraintNode{`typeof(v)==="string" && isCIDR(v)`, nil}
+}
+
+// where is one of "prefix", "suffix" and "substring"; the Fold variants
+// compare case-insensitively
+type FragmentValidator struct {
+	w, s string
+	i    bool
+}
+
+func HasPrefix(s string) Validator {
+	return FragmentValidator{"prefix", s, false}
+}
+
+func HasSuffix(s string) Validator {
+	return FragmentValidator{"suffix", s, false}
+}
+
+func ContainsSubstring(s string) Validator {
+	return FragmentValidator{"substring", s, false}
+}
+
+func HasPrefixFold(s string) Validator {
+	return FragmentValidator{"prefix", s, true}
+}
+
+func HasSuffixFold(s string) Validator {
+	return FragmentValidator{"suffix", s, true}
+}
+
+func ContainsSubstringFold(s string) Validator {
+	return FragmentValidator{"substring", s, true}
+}
+
+func (a FragmentValidator) Where() string {
+	return a.w
+}
+
+func (a FragmentValidator) Fragment() string {
+	return a.s
+}
+
+func (a FragmentValidator) Validate(v interface{}, f []string) *Error {
+	return And(String(), lambda(func(v interface{}, f []string) *Error {
+		s, x := v.(string), a.s
+		if a.i {
+			s, x = strings.ToLower(s), strings.ToLower(x)
+		}
+		switch a.w {
+		case "prefix":
+			if !strings.HasPrefix(s, x) {
+				return &Error{"string_must_have_prefix", f, a.s}
+			}
+		case "suffix":
+			if !strings.HasSuffix(s, x) {
+				return &Error{"string_must_have_suffix", f, a.s}
+			}
+		default:
+			if !strings.Contains(s, x) {
+				return &Error{"string_must_contain_substring", f, a.s}
+			}
+		}
+		return NoError
+	})).Validate(v, f)
+}
+
+func (a FragmentValidator) Traverse(v interface{}, f func(interface{}, Validator)) {
+	f(v, a)
+}
+
+func (a FragmentValidator) Walk(f func(Validator)) {
+	f(a)
+}
+
+func (a FragmentValidator) ConstraintTree() ConstraintNode {
+	q, _ := json.Marshal(a.s)
+	s, x := `v`, string(q)
+	if a.i {
+		s, x = `v.toLowerCase()`, x+`.toLowerCase()`
+	}
+	switch a.w {
+	case "prefix":
+		return ConstraintNode{`typeof(v)==="string" && ` + s + `.startsWith(` + x + `)`, nil}
+	case "suffix":
+		return ConstraintNode{`typeof(v)==="string" && ` + s + `.endsWith(` + x + `)`, nil}
+	}
+	return ConstraintNode{`typeof(v)==="string" && ` + s + `.indexOf(` + x + `) > -1`, nil}
+}
+
+// padding is required unless raw. whitespace is rejected, even the line
+// breaks encoding/base64 would otherwise skip, and so are set padding bits
+type Base64Validator struct {
+	u, r bool
+}
+
+func Base64() Validator {
+	return Base64Validator{false, false}
+}
+
+func Base64Raw() Validator {
+	return Base64Validator{false, true}
+}
+
+func Base64URL() Validator {
+	return Base64Validator{true, false}
+}
+
+func Base64URLRaw() Validator {
+	return Base64Validator{true, true}
+}
+
+func (a Base64Validator) Encoding() *base64.Encoding {
+	e := base64.StdEncoding
+	switch {
+	case a.u && a.r:
+		e = base64.RawURLEncoding
+	case a.u:
+		e = base64.URLEncoding
+	case a.r:
+		e = base64.RawStdEncoding
+	}
+	return e.Strict()
+}
+
+func (a Base64Validator) Validate(v interface{}, f []string) *Error {
+	return And(String(), lambda(func(v interface{}, f []string) *Error {
+		s := v.(string)
+		if _, err := a.Encoding().DecodeString(s); err != nil || strings.ContainsAny(s, " \t\r\n") {
+			return &Error{"value_must_be_base64", f, nil}
+		}
+		return NoError
+	})).Validate(v, f)
+}
+
+func (a Base64Validator) Traverse(v interface{}, f func(interface{}, Validator)) {
+	f(v, a)
+}
+
+func (a Base64Validator) Walk(f func(Validator)) {
+	f(a)
+}
+
+func (a Base64Validator) ConstraintTree() ConstraintNode {
+	c := "+/"
+	if a.u {
+		c = "\\-_"
+	}
+	if a.r {
+		return ConstraintNode{`typeof(v)==="string" && /^[A-Za-z0-9` + c + `]*$/.test(v) && v.length % 4 !== 1`, nil}
+	}
+	return ConstraintNode{`typeof(v)==="string" && /^[A-Za-z0-9` + c + `]*={0,2}$/.test(v) && v.length % 4 === 0`, nil}
+}
+
+type HexValidator struct{}
+
+func Hex() Validator {
+	return HexValidator{}
+}
+
+func (a HexValidator) Validate(v interface{}, f []string) *Error {
+	return And(String(), lambda(func(v interface{}, f []string) *Error {
+		if _, err := hex.DecodeString(v.(string)); err != nil {
+			return &Error{"value_must_be_hex", f, nil}
+		}
+		return NoError
+	})).Validate(v, f)
+}
+
+func (a HexValidator) Traverse(v interface{}, f func(interface{}, Validator)) {
+	f(v, a)
+}
+
+func (a HexValidator) Walk(f func(Validator)) {
+	f(a)
+}
+
+func (a HexValidator) ConstraintTree() ConstraintNode {
+	return ConstraintNode{`typeof(v)==="string" && /^([0-9a-fA-F]{2})*$/.test(v)`, nil}
+}
+
+// a CSS-style color: "#" followed by 3 or 6 hex digits, or 8 with an alpha
+// channel. the "#" is required. HexColorOpaque refuses the alpha form
+type HexColorValidator struct {
+	a bool
+}
+
+func HexColor() Validator {
+	return HexColorValidator{true}
+}
+
+func HexColorOpaque() Validator {
+	return HexColorValidator{false}
+}
+
+func (a HexColorValidator) Alpha() bool {
+	return a.a
+}
+
+func (a HexColorValidator) Validate(v interface{}, f []string) *Error {
+	return And(String(), lambda(func(v interface{}, f []string) *Error {
+		s := v.(string)
+		l := len(s) - 1
+		if !strings.HasPrefix(s, "#") || (l != 3 && l != 6 && (l != 8 || !a.a)) {
+			return &Error{"value_must_be_hex_color", f, nil}
+		}
+		for _, r := range s[1:] {
+			if !strings.ContainsRune("0123456789abcdefABCDEF", r) {
+				return &Error{"value_must_be_hex_color", f, nil}
+			}
+		}
+		return NoError
+	})).Validate(v, f)
+}
+
+func (a HexColorValidator) Traverse(v interface{}, f func(interface{}, Validator)) {
+	f(v, a)
+}
+
+func (a HexColorValidator) Walk(f func(Validator)) {
+	f(a)
+}
+
+func (a HexColorValidator) ConstraintTree() ConstraintNode {
+	if a.a {
+		return ConstraintNode{`typeof(v)==="string" && /^#([0-9a-fA-F]{3}|[0-9a-fA-F]{6}|[0-9a-fA-F]{8})$/.test(v)`, nil}
+	}
+	return ConstraintNode{`typeof(v)==="string" && /^#([0-9a-fA-F]{3}|[0-9a-fA-F]{6})$/.test(v)`, nil}
+}
+
+// ISO 8601 durations as profiled by RFC 3339 appendix A: "P1Y2M10DT2H30M",
+// "PT0.5S" or weeks on their own like "P2W". at least one component is
+// required, so "P" and "PT" are rejected. only seconds take a fraction
+var durationRegex = regexp.MustCompile(`^P(?:\d+W|(?:\d+Y)?(?:\d+M)?(?:\d+D)?(?:T(?:\d+H)?(?:\d+M)?(?:\d+(?:[.,]\d+)?S)?)?)$`)
+
+type DurationValidator struct{}
+
+func Duration() Validator {
+	return DurationValidator{}
+}
+
+func (a DurationValidator) Validate(v interface{}, f []string) *Error {
+	return And(String(), lambda(func(v interface{}, f []string) *Error {
+		s := v.(string)
+		if s == "P" || strings.HasSuffix(s, "T") || !durationRegex.MatchString(s) {
+			return &Error{"value_must_be_duration", f, nil}
+		}
+		return NoError
+	})).Validate(v, f)
+}
+
+func (a DurationValidator) Traverse(v interface{}, f func(interface{}, Validator)) {
+	f(v, a)
+}
+
+func (a DurationValidator) Walk(f func(Validator)) {
+	f(a)
+}
+
+func (a DurationValidator) ConstraintTree() ConstraintNode {
+	return ConstraintNode{`typeof(v)==="string" && /<iso8601-duration>/.test(v)`, nil}
+}
+
+// a string time.Parse accepts with layout l. layouts are written like Go's,
+// as the reference time Mon Jan 2 15:04:05 MST 2006, so "02/01/2006" is a
+// day/month/year date
+type TimeFormatValidator struct {
+	l string
+}
+
+// panics when l contains no element of the reference time or can't parse
+// what it formats
+func TimeFormat(l string) Validator {
+	r := time.Date(2009, time.November, 10, 23, 7, 8, 0, time.UTC)
+	if s := r.Format(l); s == l {
+		panic("TimeFormat: layout has no reference time elements")
+	} else if _, err := time.Parse(l, s); err != nil {
+		panic("TimeFormat: " + err.Error())
+	}
+	return TimeFormatValidator{l}
+}
+
+func (a TimeFormatValidator) Layout() string {
+	return a.l
+}
+
+func (a TimeFormatValidator) Validate(v interface{}, f []string) *Error {
+	return And(String(), lambda(func(v interface{}, f []string) *Error {
+		if _, err := time.Parse(a.l, v.(string)); err != nil {
+			return &Error{"value_must_match_time_format", f, a.l}
+		}
+		return NoError
+	})).Validate(v, f)
+}
+
+func (a TimeFormatValidator) Traverse(v interface{}, f func(interface{}, Validator)) {
+	f(v, a)
+}
+
+func (a TimeFormatValidator) Walk(f func(Validator)) {
+	f(a)
+}
+
+func (a TimeFormatValidator) ConstraintTree() ConstraintNode {
+	return ConstraintNode{`typeof(v)==="string" && /<time-format>/.test(v)`, nil}
+}
+
+// a string of digits passing the Luhn checksum, like card numbers or IMEIs.
+// with s, spaces and hyphens are skipped first, so "4111 1111 1111 1111"
+// passes too
+type LuhnValidator struct {
+	s bool
+}
+
+func Luhn() Validator {
+	return LuhnValidator{false}
+}
+
+func LuhnSeparated() Validator {
+	return LuhnValidator{true}
+}
+
+func (a LuhnValidator) Validate(v interface{}, f []string) *Error {
+	return And(String(), lambda(func(v interface{}, f []string) *Error {
+		s := v.(string)
+		if a.s {
+			s = strings.NewReplacer(" ", "", "-", "").Replace(s)
+		}
+		if s == "" {
+			return &Error{"value_must_pass_luhn", f, nil}
+		}
+		t := 0
+		for i := 0; i < len(s); i++ {
+			c := s[len(s)-1-i]
+			if c < '0' || c > '9' {
+				return &Error{"value_must_pass_luhn", f, nil}
+			}
+			d := int(c - '0')
+			if i%2 == 1 {
+				if d *= 2; d > 9 {
+					d -= 9
+				}
+			}
+			t += d
+		}
+		if t%10 != 0 {
+			return &Error{"value_must_pass_luhn", f, nil}
+		}
+		return NoError
+	})).Validate(v, f)
+}
+
+func (a LuhnValidator) Traverse(v interface{}, f func(interface{}, Validator)) {
+	f(v, a)
+}
+
+func (a LuhnValidator) Walk(f func(Validator)) {
+	f(a)
+}
+
+func (a LuhnValidator) ConstraintTree() ConstraintNode {
+	return ConstraintNode{`typeof(v)==="string" && luhn(v)`, nil}
+}
+
+type SemVersion struct {
+	Major, Minor, Patch uint64
+	Prerelease, Build   []string
+}
+
+// parses a SemVer 2.0.0 version. a "v" prefix isn't part of the spec and
+// is rejected
+func ParseSemVer(s string) (SemVersion, bool) {
+	r := SemVersion{}
+	if i := strings.IndexByte(s, '+'); i > -1 {
+		r.Build = strings.Split(s[i+1:], ".")
+		for _, b := range r.Build {
+			if !semVerIdentifier(b) {
+				return r, false
+			}
+		}
+		s = s[:i]
+	}
+	if i := strings.IndexByte(s, '-'); i > -1 {
+		r.Prerelease = strings.Split(s[i+1:], ".")
+		for _, p := range r.Prerelease {
+			if !semVerIdentifier(p) || (semVerNumeric(p) && len(p) > 1 && p[0] == '0') {
+				return r, false
+			}
+		}
+		s = s[:i]
+	}
+	c := strings.Split(s, ".")
+	if len(c) != 3 {
+		return r, false
+	}
+	ns := []*uint64{&r.Major, &r.Minor, &r.Patch}
+	for i, n := range c {
+		if !semVerNumeric(n) || (len(n) > 1 && n[0] == '0') {
+			return r, false
+		}
+		u, err := strconv.ParseUint(n, 10, 64)
+		if err != nil {
+			return r, false
+		}
+		*ns[i] = u
+	}
+	return r, true
+}
+
+func semVerIdentifier(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, c := range s {
+		if !(c >= '0' && c <= '9' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c == '-') {
+			return false
+		}
+	}
+	return true
+}
+
+func semVerNumeric(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, c := range s {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// see ParseSemVer
+type SemVerValidator struct{}
+
+func SemVer() Validator {
+	return SemVerValidator{}
+}
+
+func (a SemVerValidator) Validate(v interface{}, f []string) *Error {
+	return And(String(), lambda(func(v interface{}, f []string) *Error {
+		if _, k := ParseSemVer(v.(string)); !k {
+			return &Error{"value_must_be_semver", f, nil}
+		}
+		return NoError
+	})).Validate(v, f)
+}
+
+func (a SemVerValidator) Traverse(v interface{}, f func(interface{}, Validator)) {
+	f(v, a)
+}
+
+func (a SemVerValidator) Walk(f func(Validator)) {
+	f(a)
+}
+
+func (a SemVerValidator) ConstraintTree() ConstraintNode {
+	return ConstraintNode{`typeof(v)==="string" && /<semver>/.test(v)`, nil}
+}
+
+// ASCII allows every rune up to and including DEL (0x7f), NUL too. Printable
+// rejects control characters as per unicode.IsControl, so both NUL and DEL,
+// but lets any other rune including emoji through
+type CharsetValidator struct {
+	p bool
+}
+
+func ASCII() Validator {
+	return CharsetValidator{false}
+}
+
+func Printable() Validator {
+	return CharsetValidator{true}
+}
+
+func (a CharsetValidator) Validate(v interface{}, f []string) *Error {
+	return And(String(), lambda(func(v interface{}, f []string) *Error {
+		s := v.(string)
+		for i := 0; i < len(s); {
+			r, n := utf8.DecodeRuneInString(s[i:])
+			if a.p && unicode.IsControl(r) {
+				return &Error{"string_must_be_printable", f, i}
+			}
+			if !a.p && r > unicode.MaxASCII {
+				return &Error{"string_must_be_ascii", f, i}
+			}
+			i += n
+		}
+		return NoError
+	})).Validate(v, f)
+}
+
+func (a CharsetValidator) Traverse(v interface{}, f func(interface{}, Validator)) {
+	f(v, a)
+}
+
+func (a CharsetValidator) Walk(f func(Validator)) {
+	f(a)
+}
+
+func (a CharsetValidator) ConstraintTree() ConstraintNode {
+	if a.p {
+		return ConstraintNode{`typeof(v)==="string" && !/[\u0000-\u001f\u007f-\u009f]/.test(v)`, nil}
+	}
+	return ConstraintNode{`typeof(v)==="string" && /^[\u0000-\u007f]*$/.test(v)`, nil}
+}
+
+// a string safe to export to CSV or XML: no byte order mark anywhere and no
+// C0 or C1 control character, NUL and DEL included. CleanStringWhitespace
+// lets tabs, line feeds and carriage returns through. the context holds the
+// first forbidden rune, like "U+FEFF", and its byte offset
+type CleanStringValidator struct {
+	w bool
+}
+
+func CleanString() Validator {
+	return CleanStringValidator{false}
+}
+
+func CleanStringWhitespace() Validator {
+	return CleanStringValidator{true}
+}
+
+func (a CleanStringValidator) Validate(v interface{}, f []string) *Error {
+	return And(String(), lambda(func(v interface{}, f []string) *Error {
+		for i, r := range v.(string) {
+			if a.w && (r == '\t' || r == '\n' || r == '\r') {
+				continue
+			}
+			if r == '\ufeff' || unicode.IsControl(r) {
+				return &Error{"string_contains_forbidden_characters", f, map[string]interface{}{"rune": fmt.Sprintf("%U", r), "position": i}}
+			}
+		}
+		return NoError
+	})).Validate(v, f)
+}
+
+func (a CleanStringValidator) Traverse(v interface{}, f func(interface{}, Validator)) {
+	f(v, a)
+}
+
+func (a CleanStringValidator) Walk(f func(Validator)) {
+	f(a)
+}
+
+func (a CleanStringValidator) ConstraintTree() ConstraintNode {
+	if a.w {
+		return ConstraintNode{`typeof(v)==="string" && !/[\u0000-\u0008\u000b\u000c\u000e-\u001f\u007f-\u009f\ufeff]/.test(v)`, nil}
+	}
+	return ConstraintNode{`typeof(v)==="string" && !/[\u0000-\u001f\u007f-\u009f\ufeff]/.test(v)`, nil}
+}
+
+type LengthBetweenValidator struct {
+	x, y int
+}
+
+func LengthBetween(x, y int) Validator {
+	if y < x {
+		panic("LengthBetween: y < x")
+	}
+	return LengthBetweenValidator{x, y}
+}
+
+func (a LengthBetweenValidator) Validate(v interface{}, f []string) *Error {
+	return And(Or(String(), Array(Anything())), lambda(func(v interface{}, f []string) *Error {
+		if l := length(v); l < a.x || l > a.y {
+			if a.x == a.y {
+				return &Error{"value_must_have_length", f, a.x}
+			}
+			return &Error{"value_must_have_length_between", f, map[string]int{"min": a.x, "max": a.y}}
+		}
+		return NoError
+	})).Validate(v, f)
+}
+
+func (a LengthBetweenValidator) Min() int {
+	return a.x
+}
+
+func (a LengthBetweenValidator) Max() int {
+	return a.y
+}
+
+func (a LengthBetweenValidator) Traverse(v interface{}, f func(interface{}, Validator)) {
+	f(v, a)
+}
+
+func (a LengthBetweenValidator) Walk(f func(Validator)) {
+	f(a)
+}
+
+func (a LengthBetweenValidator) ConstraintTree() ConstraintNode {
+	return ConstraintNode{`(typeof(v)==="string" && v.length >= min && v.length <= max) || (typeof(v)==="array" && v.length >= min && v.length <= max)`, nil}
+}
+
+func Length(x int) Validator {
+	return LengthBetween(x, x)
+}
+
+// a string of x to y user-perceived characters: grapheme clusters per
+// Unicode's segmentation rules, so a flag, an emoji with a skin tone or a
+// letter with combining accents counts once where LengthBetween counts
+// every rune
+type GraphemeLengthBetweenValidator struct {
+	x, y int
+}
+
+func GraphemeLengthBetween(x, y int) Validator {
+	if y < x {
+		panic("GraphemeLengthBetween: y < x")
+	}
+	return GraphemeLengthBetweenValidator{x, y}
+}
+
+func (a GraphemeLengthBetweenValidator) Min() int {
+	return a.x
+}
+
+func (a GraphemeLengthBetweenValidator) Max() int {
+	return a.y
+}
+
+func (a GraphemeLengthBetweenValidator) Validate(v interface{}, f []string) *Error {
+	return And(String(), lambda(func(v interface{}, f []string) *Error {
+		if l := uniseg.GraphemeClusterCount(v.(string)); l < a.x || l > a.y {
+			return &Error{"string_must_have_grapheme_length_between", f, map[string]int{"min": a.x, "max": a.y}}
+		}
+		return NoError
+	})).Validate(v, f)
+}
+
+func (a GraphemeLengthBetweenValidator) Traverse(v interface{}, f func(interface{}, Validator)) {
+	f(v, a)
+}
+
+func (a GraphemeLengthBetweenValidator) Walk(f func(Validator)) {
+	f(a)
+}
+
+func (a GraphemeLengthBetweenValidator) ConstraintTree() ConstraintNode {
+	return ConstraintNode{`typeof(v)==="string" && [...new Intl.Segmenter().segment(v)].length >= min && [...new Intl.Segmenter().segment(v)].length <= max`, nil}
+}
+
+type LengthMinValidator struct {
+	x int
+}
+
+func LengthMin(x int) Validator {
+	return LengthMinValidator{x}
+}
+
+func (a LengthMinValidator) Validate(v interface{}, f []string) *Error {
+	return And(Or(String(), Array(Anything())), lambda(func(v interface{}, f []string) *Error {
+		if length(v) < a.x {
+			return &Error{"value_must_have_length_at_least", f, a.x}
+		}
+		return NoError
+	})).Validate(v, f)
+}
+
+func (a LengthMinValidator) Min() int {
+	return a.x
+}
+
+func (a LengthMinValidator) Traverse(v interface{}, f func(interface{}, Validator)) {
+	f(v, a)
+}
+
+func (a LengthMinValidator) Walk(f func(Validator)) {
+	f(a)
+}
+
+func (a LengthMinValidator) ConstraintTree() ConstraintNode {
+	return ConstraintNode{`(typeof(v)==="string" || typeof(v)==="array") && v.length >= min`, nil}
+}
+
+type LengthMaxValidator struct {
+	y int
+}
+
+func LengthMax(y int) Validator {
+	return LengthMaxValidator{y}
+}
+
+func (a LengthMaxValidator) Validate(v interface{}, f []string) *Error {
+	return And(Or(String(), Array(Anything())), lambda(func(v interface{}, f []string) *Error {
+		if length(v) > a.y {
+			return &Error{"value_must_have_length_at_most", f, a.y}
+		}
+		return NoError
+	})).Validate(v, f)
+}
+
+func (a LengthMaxValidator) Max() int {
+	return a.y
+}
+
+func (a LengthMaxValidator) Traverse(v interface{}, f func(interface{}, Validator)) {
+	f(v, a)
+}
+
+func (a LengthMaxValidator) Walk(f func(Validator)) {
+	f(a)
+}
+
+func (a LengthMaxValidator) ConstraintTree() ConstraintNode {
+	return ConstraintNode{`(typeof(v)==="string" || typeof(v)==="array") && v.length <= max`, nil}
+}
+
+// counts the bytes of a string's UTF-8 encoding rather than its runes, for
+// storage limits
+type MaxByteLengthValidator struct {
+	n int
+}
+
+func MaxByteLength(n int) Validator {
+	if n < 0 {
+		panic("MaxByteLength: n < 0")
+	}
+	return MaxByteLengthValidator{n}
+}
+
+func (a MaxByteLengthValidator) Max() int {
+	return a.n
+}
+
+func (a MaxByteLengthValidator) Validate(v interface{}, f []string) *Error {
+	return And(String(), lambda(func(v interface{}, f []string) *Error {
+		if len(v.(string)) > a.n {
+			return &Error{"string_exceeds_byte_length", f, a.n}
+		}
+		return NoError
+	})).Validate(v, f)
+}
+
+func (a MaxByteLengthValidator) Traverse(v interface{}, f func(interface{}, Validator)) {
+	f(v, a)
+}
+
+func (a MaxByteLengthValidator) Walk(f func(Validator)) {
+	f(a)
+}
+
+func (a MaxByteLengthValidator) ConstraintTree() ConstraintNode {
+	return ConstraintNode{`typeof(v)==="string" && new TextEncoder().encode(v).length <= max`, nil}
+}
+
+// runes for strings, elements for arrays
+func length(v interface{}) int {
+	switch t := v.(type) {
+	case string:
+		return utf8.RuneCountInString(t)
+	case []interface{}:
+		return len(t)
+	}
+	return -1
+}
+
+// estimates entropy as the string's Shannon entropy per rune, computed from
+// its own rune frequencies, times its rune count. this rewards length and
+// variety but knows nothing about dictionary words
+type MinEntropyBitsValidator struct {
+	b float64
+}
+
+func MinEntropyBits(b float64) Validator {
+	return MinEntropyBitsValidator{b}
+}
+
+func (a MinEntropyBitsValidator) Validate(v interface{}, f []string) *Error {
+	return And(String(), lambda(func(v interface{}, f []string) *Error {
+		if e := EntropyBits(v.(string)); e < a.b {
+			return &Error{"value_entropy_too_low", f, map[string]float64{"min": a.b, "actual": e}}
+		}
+		return NoError
+	})).Validate(v, f)
+}
+
+func (a MinEntropyBitsValidator) Bits() float64 {
+	return a.b
+}
+
+func (a MinEntropyBitsValidator) Traverse(v interface{}, f func(interface{}, Validator)) {
+	f(v, a)
+}
+
+func (a MinEntropyBitsValidator) Walk(f func(Validator)) {
+	f(a)
+}
+
+func (a MinEntropyBitsValidator) ConstraintTree() ConstraintNode {
+	return ConstraintNode{`typeof(v)==="string" && entropy(v) >= bits`, nil}
+}
+
+func EntropyBits(s string) float64 {
+	c, n := make(map[rune]float64), 0.0
+	for _, r := range s {
+		c[r]++
+		n++
+	}
+	h := 0.0
+	for _, k := range c {
+		p := k / n
+		h -= p * math.Log2(p)
+	}
+	return h * n
+}
+
+type NumberMinValidator struct {
+	x float64
+}
+
+func NumberMin(x float64) Validator {
+	return NumberMinValidator{x}
+}
+
+func (a NumberMinValidator) Validate(v interface{}, f []string) *Error {
+	return And(Number(), lambda(func(v interface{}, f []string) *Error {
+		if !(v.(float64) >= a.x) {
+			return &Error{"value_must_be_at_least", f, a.x}
+		}
+		return NoError
+	})).Validate(v, f)
+}
+
+func (a NumberMinValidator) Min() float64 {
+	return a.x
+}
+
+func (a NumberMinValidator) Traverse(v interface{}, f func(interface{}, Validator)) {
+	f(v, a)
+}
+
+func (a NumberMinValidator) Walk(f func(Validator)) {
+	f(a)
+}
+
+func (a NumberMinValidator) ConstraintTree() ConstraintNode {
+	return ConstraintNode{`typeof(v)==="number" && v >= min`, nil}
+}
+
+type NumberMaxValidator struct {
+	y float64
+}
+
+func NumberMax(y float64) Validator {
+	return NumberMaxValidator{y}
+}
+
+func (a NumberMaxValidator) Validate(v interface{}, f []string) *Error {
+	return And(Number(), lambda(func(v interface{}, f []string) *Error {
+		if !(v.(float64) <= a.y) {
+			return &Error{"value_must_be_at_most", f, a.y}
+		}
+		return NoError
+	})).Validate(v, f)
+}
+
+func (a NumberMaxValidator) Max() float64 {
+	return a.y
+}
+
+func (a NumberMaxValidator) Traverse(v interface{}, f func(interface{}, Validator)) {
+	f(v, a)
+}
+
+func (a NumberMaxValidator) Walk(f func(Validator)) {
+	f(a)
+}
+
+func (a NumberMaxValidator) ConstraintTree() ConstraintNode {
+	return ConstraintNode{`typeof(v)==="number" && v <= max`, nil}
+}
+
+type FiniteNumberValidator struct{}
+
+func FiniteNumber() Validator {
+	return FiniteNumberValidator{}
+}
+
+func (a FiniteNumberValidator) Validate(v interface{}, f []string) *Error {
+	return And(Number(), lambda(func(v interface{}, f []string) *Error {
+		if n := v.(float64); math.IsNaN(n) || math.IsInf(n, 0) {
+			return &Error{"value_must_be_finite", f, nil}
+		}
+		return NoError
+	})).Validate(v, f)
+}
+
+func (a FiniteNumberValidator) Traverse(v interface{}, f func(interface{}, Validator)) {
+	f(v, a)
+}
+
+func (a FiniteNumberValidator) Walk(f func(Validator)) {
+	f(a)
+}
+
+func (a FiniteNumberValidator) ConstraintTree() ConstraintNode {
+	return ConstraintNode{`typeof(v)==="number" && isFinite(v)`, nil}
+}
+
+// a latitude in degrees, -90 to 90
+type LatitudeValidator struct{}
+
+func Latitude() Validator {
+	return LatitudeValidator{}
+}
+
+func (a LatitudeValidator) Validate(v interface{}, f []string) *Error {
+	return And(Number(), lambda(func(v interface{}, f []string) *Error {
+		if l := v.(float64); !(l >= -90 && l <= 90) {
+			return &Error{"value_must_be_latitude", f, nil}
+		}
+		return NoError
+	})).Validate(v, f)
+}
+
+func (a LatitudeValidator) Traverse(v interface{}, f func(interface{}, Validator)) {
+	f(v, a)
+}
+
+func (a LatitudeValidator) Walk(f func(Validator)) {
+	f(a)
+}
+
+func (a LatitudeValidator) ConstraintTree() ConstraintNode {
+	return ConstraintNode{`typeof(v)==="number" && v >= -90 && v <= 90`, nil}
+}
+
+// a longitude in degrees, -180 to 180
+type LongitudeValidator struct{}
+
+func Longitude() Validator {
+	return LongitudeValidator{}
+}
+
+func (a LongitudeValidator) Validate(v interface{}, f []string) *Error {
+	return And(Number(), lambda(func(v interface{}, f []string) *Error {
+		if l := v.(float64); !(l >= -180 && l <= 180) {
+			return &Error{"value_must_be_longitude", f, nil}
+		}
+		return NoError
+	})).Validate(v, f)
+}
+
+func (a LongitudeValidator) Traverse(v interface{}, f func(interface{}, Validator)) {
+	f(v, a)
+}
+
+func (a LongitudeValidator) Walk(f func(Validator)) {
+	f(a)
+}
+
+func (a LongitudeValidator) ConstraintTree() ConstraintNode {
+	return ConstraintNode{`typeof(v)==="number" && v >= -180 && v <= 180`, nil}
+}
+
+// either a [lon, lat] array, in GeoJSON order, or an object with exactly
+// the keys "lat" and "lon", both required
+type GeoPointValidator struct{}
+
+func GeoPoint() Validator {
+	return GeoPointValidator{}
+}
+
+func (a GeoPointValidator) Validate(v interface{}, f []string) *Error {
+	switch t := v.(type) {
+	case []interface{}:
+		if len(t) != 2 {
+			return &Error{"value_must_be_geo_point", f, nil}
+		}
+		if e := Longitude().Validate(t[0], append(f, "0")); e != nil {
+			return e
+		}
+		return Latitude().Validate(t[1], append(f, "1"))
+	case map[string]interface{}:
+		return Object(map[string]Validator{"lat": Latitude(), "lon": Longitude()}).Validate(v, f)
+	}
+	return &Error{"value_must_be_geo_point", f, nil}
+}
+
+func (a GeoPointValidator) Traverse(v interface{}, f func(interface{}, Validator)) {
+	f(v, a)
+}
+
+func (a GeoPointValidator) Walk(f func(Validator)) {
+	f(a)
+}
+
+func (a GeoPointValidator) ConstraintTree() ConstraintNode {
+	return ConstraintNode{`(Array.isArray(v) && v.length===2 && <lon>(v[0]) && <lat>(v[1])) || (typeof(v)==="object" && v.keys()===["lat","lon"] && <lat>(v.lat) && <lon>(v.lon))`, nil}
+}
+
+var currencyRegex = regexp.MustCompile(`^\d+(\.\d{0,2}0*)?$`)
+
+// a non-negative number with at most two decimal places. the places are
+// counted on the number as written for a json.Number and on the shortest
+// representation of a float64, so 0.1+0.2 is rejected but 0.3 isn't
+type CurrencyAmountValidator struct{}
+
+func CurrencyAmount() Validator {
+	return CurrencyAmountValidator{}
+}
+
+func (a CurrencyAmountValidator) Validate(v interface{}, f []string) *Error {
+	var s string
+	switch n := v.(type) {
+	case float64:
+		s = strconv.FormatFloat(n, 'f', -1, 64)
+	case json.Number:
+		x, err := n.Float64()
+		if err != nil {
+			return &Error{"value_must_be_number", f, nil}
+		}
+		s = n.String()
+		if strings.ContainsAny(s, "eE") {
+			s = strconv.FormatFloat(x, 'f', -1, 64)
+		}
+	default:
+		return &Error{"value_must_be_number", f, nil}
+	}
+	if !currencyRegex.MatchString(s) {
+		return &Error{"value_must_be_currency_amount", f, nil}
+	}
+	return NoError
+}
+
+func (a CurrencyAmountValidator) Traverse(v interface{}, f func(interface{}, Validator)) {
+	f(v, a)
+}
+
+func (a CurrencyAmountValidator) Walk(f func(Validator)) {
+	f(a)
+}
+
+func (a CurrencyAmountValidator) ConstraintTree() ConstraintNode {
+	return ConstraintNode{`typeof(v)==="number" && v >= 0 && /^\d+(\.\d{1,2})?$/.test(String(v))`, nil}
+}
+
+type NumberBetweenValidator struct {
+	x, y float64
+}
+
+func NumberBetween(x, y float64) Validator {
+	if y < x {
+		panic("NumberBetween: y < x")
+	}
+	return NumberBetweenValidator{x, y}
+}
+
+func (a NumberBetweenValidator) Validate(v interface{}, f []string) *Error {
+	return And(Number(), lambda(func(v interface{}, f []string) *Error {
+		l := v.(float64)
+		if !(l >= a.x && l <= a.y) { // NaN fails every comparison
+			return &Error{"value_must_have_value_between", f, map[string]float64{"min": a.x, "max": a.y}}
+		}
+		return NoError
+	})).Validate(v, f)
+}
+
+func (a NumberBetweenValidator) Traverse(v interface{}, f func(interface{}, Validator)) {
+	f(v, a)
+}
+
+func (a NumberBetweenValidator) Walk(f func(Validator)) {
+	f(a)
+}
+
+func (a NumberBetweenValidator) ConstraintTree() ConstraintNode {
+	return ConstraintNode{`typeof(v)==="number" && v >= min && v =< max`, nil}
+}
+
+// a number from 0 to y inclusive: Percentage up to 100, Fraction up to 1
+type ProportionValidator struct {
+	y float64
+}
+
+func Percentage() Validator {
+	return ProportionValidator{100}
+}
+
+func Fraction() Validator {
+	return ProportionValidator{1}
+}
+
+func (a ProportionValidator) Max() float64 {
+	return a.y
+}
+
+func (a ProportionValidator) Validate(v interface{}, f []string) *Error {
+	return And(Number(), lambda(func(v interface{}, f []string) *Error {
+		if l := v.(float64); !(l >= 0 && l <= a.y) { // NaN fails every comparison
+			if a.y == 1 {
+				return &Error{"value_must_be_fraction", f, nil}
+			}
+			return &Error{"value_must_be_percentage", f, nil}
+		}
+		return NoError
+	})).Validate(v, f)
+}
+
+func (a ProportionValidator) Traverse(v interface{}, f func(interface{}, Validator)) {
+	f(v, a)
+}
+
+func (a ProportionValidator) Walk(f func(Validator)) {
+	f(a)
+}
+
+func (a ProportionValidator) ConstraintTree() ConstraintNode {
+	return ConstraintNode{`typeof(v)==="number" && v >= 0 && v <= max`, nil}
+}
+
+// neither 0.1 nor most of its multiples are exact in float64: 0.3 / 0.1 is
+// 2.9999999999999996 and math.Mod(0.3, 0.1) is 0.09999999999999998. so
+// instead of testing the remainder against zero, v / n is accepted when it's
+// within a few ulps of a whole number
+type MultipleOfValidator struct {
+	n float64
+}
+
+func MultipleOf(n float64) Validator {
+	if n <= 0 || math.IsInf(n, 0) || math.IsNaN(n) {
+		panic("MultipleOf: n must be positive and finite")
+	}
+	return MultipleOfValidator{n}
+}
+
+func (a MultipleOfValidator) Validate(v interface{}, f []string) *Error {
+	return And(Number(), lambda(func(v interface{}, f []string) *Error {
+		q := v.(float64) / a.n
+		if math.Abs(q-math.Round(q)) > 4*0x1p-52*math.Abs(q) {
+			return &Error{"value_must_be_multiple_of", f, a.n}
+		}
+		return NoError
+	})).Validate(v, f)
+}
+
+func (a MultipleOfValidator) Divisor() float64 {
+	return a.n
+}
+
+func (a MultipleOfValidator) Traverse(v interface{}, f func(interface{}, Validator)) {
+	f(v, a)
+}
+
+func (a MultipleOfValidator) Walk(f func(Validator)) {
+	f(a)
+}
+
+func (a MultipleOfValidator) ConstraintTree() ConstraintNode {
+	return ConstraintNode{`typeof(v)==="number" && v % n === 0`, nil}
+}
+
+// -0 counts as zero: it's neither positive nor negative
+type SignValidator struct {
+	s string
+}
+
+func Positive() Validator {
+	return SignValidator{"positive"}
+}
+
+func Negative() Validator {
+	return SignValidator{"negative"}
+}
+
+func NonNegative() Validator {
+	return SignValidator{"non_negative"}
+}
+
+func NonPositive() Validator {
+	return SignValidator{"non_positive"}
+}
+
+func (a SignValidator) Sign() string {
+	return a.s
+}
+
+func (a SignValidator) Validate(v interface{}, f []string) *Error {
+	return And(Number(), lambda(func(v interface{}, f []string) *Error {
+		n, k := v.(float64), false
+		switch a.s {
+		case "positive":
+			k = n > 0
+		case "negative":
+			k = n < 0
+		case "non_negative":
+			k = n >= 0
+		case "non_positive":
+			k = n <= 0
+		}
+		if !k {
+			return &Error{"value_must_be_" + a.s, f, nil}
+		}
+		return NoError
+	})).Validate(v, f)
+}
+
+func (a SignValidator) Traverse(v interface{}, f func(interface{}, Validator)) {
+	f(v, a)
+}
+
+func (a SignValidator) Walk(f func(Validator)) {
+	f(a)
+}
+
+func (a SignValidator) ConstraintTree() ConstraintNode {
+	c := map[string]string{"positive": "v > 0", "negative": "v < 0", "non_negative": "v >= 0", "non_positive": "v <= 0"}[a.s]
+	return ConstraintNode{`typeof(v)==="number" && ` + c, nil}
+}
+
+type WholeNumberValidator struct{}
+
+func WholeNumber() Validator {
+	return WholeNumberValidator{}
+}
+
+func (a WholeNumberValidator) Validate(v interface{}, f []string) *Error {
+	return And(Number(), lambda(func(v interface{}, f []string) *Error {
+		n := v.(float64)
+		_, r := math.Modf(n)
+		if r != 0 {
+			return &Error{"value_must_be_whole_number", f, nil}
+		}
+		return NoError
+	})).Validate(v, f)
+}
+
+func (a WholeNumberValidator) Traverse(v interface{}, f func(interface{}, Validator)) {
+	f(v, a)
+}
+
+func (a WholeNumberValidator) Walk(f func(Validator)) {
+	f(a)
+}
+
+func (a WholeNumberValidator) ConstraintTree() ConstraintNode {
+	return ConstraintNode{`typeof(v)==="number" && (v % 1 === 0)`, nil}
+}
+
+type WholeNumberBetweenValidator struct {
+	x, y int
+}
+
+func WholeNumberBetween(x, y int) Validator {
+	if y < x {
+		panic("WholeNumberBetween: y < x")
+	}
+	return WholeNumberBetweenValidator{x, y}
+}
+
+func (a WholeNumberBetweenValidator) Validate(v interface{}, f []string) *Error {
+	return And(WholeNumber(), NumberBetween(float64(a.x), float64(a.y))).Validate(v, f)
+}
+
+func (a WholeNumberBetweenValidator) Traverse(v interface{}, f func(interface{}, Validator)) {
+	f(v, a)
+}
+
+func (a WholeNumberBetweenValidator) Walk(f func(Validator)) {
+	f(a)
+}
+
+func (a WholeNumberBetweenValidator) ConstraintTree() ConstraintNode {
+	return ConstraintNode{`typeof(v)==="number" && (v % 1 === 0) && v >= min && v <= max`, nil}
+}
+
+// like WholeNumberBetween, also accepting json.Number. Int converts a value
+// that passed to an int, sparing callers the float64 round trip
+type IntBetweenValidator struct {
+	x, y int
+}
+
+func IntBetween(x, y int) Validator {
+	if y < x {
+		panic("IntBetween: y < x")
+	}
+	return IntBetweenValidator{x, y}
+}
+
+func (a IntBetweenValidator) Min() int {
+	return a.x
+}
+
+func (a IntBetweenValidator) Max() int {
+	return a.y
+}
+
+func (a IntBetweenValidator) Int(v interface{}) (int, bool) {
+	i, k := parseInt64(v)
+	if !k || i < int64(a.x) || i > int64(a.y) {
+		return 0, false
+	}
+	return int(i), true
+}
+
+func (a IntBetweenValidator) Validate(v interface{}, f []string) *Error {
+	if _, k := a.Int(v); !k {
+		return &Error{"value_must_be_int_between", f, map[string]int{"min": a.x, "max": a.y}}
+	}
+	return NoError
+}
+
+func (a IntBetweenValidator) Traverse(v interface{}, f func(interface{}, Validator)) {
+	f(v, a)
+}
+
+func (a IntBetweenValidator) Walk(f func(Validator)) {
+	f(a)
+}
+
+func (a IntBetweenValidator) ConstraintTree() ConstraintNode {
+	return ConstraintNode{`typeof(v)==="number" && (v % 1 === 0) && v >= min && v <= max`, nil}
+}
+
+// validates a whole number is one of the keys of c. the error context is c,
+// so clients can list the allowed codes with their names
+type EnumIntValidator struct {
+	c map[int]string
+}
+
+func EnumInt(c map[int]string) Validator {
+	if len(c) == 0 {
+		panic("EnumInt: no codes")
+	}
+	return EnumIntValidator{c}
+}
+
+func (a EnumIntValidator) Codes() map[int]string {
+	return a.c
+}
+
+func (a EnumIntValidator) Validate(v interface{}, f []string) *Error {
+	i, l := parseInt64(v)
+	if _, k := a.c[int(i)]; !l || int64(int(i)) != i || !k {
+		return &Error{"value_not_in_int_enum", f, a.c}
+	}
+	return NoError
+}
+
+func (a EnumIntValidator) Traverse(v interface{}, f func(interface{}, Validator)) {
+	f(v, a)
+}
+
+func (a EnumIntValidator) Walk(f func(Validator)) {
+	f(a)
+}
+
+func (a EnumIntValidator) ConstraintTree() ConstraintNode {
+	return ConstraintNode{`typeof(v)==="number" && (v in codes)`, nil}
+}
+
+// a signed 64-bit integer, IntBits(64). a json.Number is parsed without
+// going through float64, so no precision is lost above 2^53
+func Int64() Validator {
+	return IntWidthValidator{64, false}
+}
+
+type Int64BetweenValidator struct {
+	x, y int64
+}
+
+func Int64Between(x, y int64) Validator {
+	if y < x {
+		panic("Int64Between: y < x")
+	}
+	return Int64BetweenValidator{x, y}
+}
+
+func (a Int64BetweenValidator) Validate(v interface{}, f []string) *Error {
+	return And(Int64(), lambda(func(v interface{}, f []string) *Error {
+		i, _ := parseInt64(v)
+		if i < a.x || i > a.y {
+			return &Error{"value_must_have_value_between", f, map[string]int64{"min": a.x, "max": a.y}}
+		}
+		return NoError
+	})).Validate(v, f)
+}
+
+func (a Int64BetweenValidator) Min() int64 {
+	return a.x
+}
+
+func (a Int64BetweenValidator) Max() int64 {
+	return a.y
+}
+
+func (a Int64BetweenValidator) Traverse(v interface{}, f func(interface{}, Validator)) {
+	f(v, a)
+}
+
+func (a Int64BetweenValidator) Walk(f func(Validator)) {
+	f(a)
+}
+
+func (a Int64BetweenValidator) ConstraintTree() ConstraintNode {
+	return ConstraintNode{`((typeof(v)==="number" && (v % 1 === 0)) || typeof(v)==="bigint") && v >= min && v <= max`, nil}
+}
+
+// a whole number that fits a b-bit integer column, signed or unsigned. like
+// Int64, a json.Number is parsed as it is, so the edges of the range are
+// exact even where float64 isn't. the context of "value_out_of_range" holds
+// the min and max
+type IntWidthValidator struct {
+	b uint
+	u bool
+}
+
+func Int8() Validator {
+	return IntWidthValidator{8, false}
+}
+
+func Int16() Validator {
+	return IntWidthValidator{16, false}
+}
+
+func Int32() Validator {
+	return IntWidthValidator{32, false}
+}
+
+func Uint8() Validator {
+	return IntWidthValidator{8, true}
+}
+
+func Uint16() Validator {
+	return IntWidthValidator{16, true}
+}
+
+func Uint32() Validator {
+	return IntWidthValidator{32, true}
+}
+
+func Uint64() Validator {
+	return IntWidthValidator{64, true}
+}
+
+// the signed b-bit validator, b being 8, 16, 32 or 64. IntBits(64) is Int64
+func IntBits(b int) Validator {
+	if b != 8 && b != 16 && b != 32 && b != 64 {
+		panic("IntBits: b must be 8, 16, 32 or 64")
+	}
+	return IntWidthValidator{uint(b), false}
+}
+
+// the unsigned b-bit validator, b being 8, 16, 32 or 64
+func UintBits(b int) Validator {
+	if b != 8 && b != 16 && b != 32 && b != 64 {
+		panic("UintBits: b must be 8, 16, 32 or 64")
+	}
+	return IntWidthValidator{uint(b), true}
+}
+
+func (a IntWidthValidator) Bits() int {
+	return int(a.b)
+}
+
+func (a IntWidthValidator) Unsigned() bool {
+	return a.u
+}
+
+func (a IntWidthValidator) Min() int64 {
+	if a.u {
+		return 0
+	}
+	return -1 << (a.b - 1)
+}
+
+func (a IntWidthValidator) Max() uint64 {
+	if a.u {
+		return 1<<a.b - 1
+	}
+	return 1<<(a.b-1) - 1
+}
+
+func (a IntWidthValidator) Validate(v interface{}, f []string) *Error {
+	r := &Error{"value_out_of_range", f, map[string]interface{}{"min": a.Min(), "max": a.Max()}}
+	n, k := v.(float64)
+	if t, x := v.(json.Number); x {
+		i, err := strconv.ParseInt(string(t), 10, 64)
+		if err == nil {
+			if i < a.Min() || (i > 0 && uint64(i) > a.Max()) {
+				return r
+			}
+			return NoError
+		}
+		if u, err := strconv.ParseUint(string(t), 10, 64); err == nil {
+			if u > a.Max() {
+				return r
+			}
+			return NoError
+		}
+		if err.(*strconv.NumError).Err == strconv.ErrRange {
+			return r
+		}
+		// exponents, like "1e3", or "1e400" which overflows float64
+		n, err := t.Float64()
+		if err != nil && math.IsInf(n, 0) {
+			return r
+		}
+		if err != nil {
+			return &Error{"value_must_be_whole_number", f, nil}
+		}
+		return a.Validate(n, f)
+	}
+	if !k || math.IsInf(n, 0) || math.Trunc(n) != n {
+		return &Error{"value_must_be_whole_number", f, nil}
+	}
+	if n >= 0 && (n >= 1<<64 || uint64(n) > a.Max()) || n < 0 && (n < -1<<63 || int64(n) < a.Min()) {
+		return r
+	}
+	return NoError
+}
+
+func (a IntWidthValidator) Traverse(v interface{}, f func(interface{}, Validator)) {
+	f(v, a)
+}
+
+func (a IntWidthValidator) Walk(f func(Validator)) {
+	f(a)
+}
+
+func (a IntWidthValidator) ConstraintTree() ConstraintNode {
+	return ConstraintNode{`((typeof(v)==="number" && (v % 1 === 0)) || typeof(v)==="bigint") && v >= min && v <= max`, nil}
+}
+
+// permission bits, 0 to 0o7777 unless restricted further with FileModeBits.
+// FileModeString takes octal strings like "644", "0644" or "0o644" instead
+type FileModeValidator struct {
+	m int64
+	s bool
+}
+
+func FileMode() Validator {
+	return FileModeValidator{07777, false}
+}
+
+func FileModeBits(m int64) Validator {
+	if m&^07777 != 0 {
+		panic("FileModeBits: mask exceeds 0o7777")
+	}
+	return FileModeValidator{m, false}
+}
+
+func FileModeString() Validator {
+	return FileModeValidator{07777, true}
+}
+
+func (a FileModeValidator) Mask() int64 {
+	return a.m
+}
+
+func (a FileModeValidator) Validate(v interface{}, f []string) *Error {
+	g := Int64()
+	if a.s {
+		g = String()
+	}
+	return And(g, lambda(func(v interface{}, f []string) *Error {
+		var i int64
+		if a.s {
+			var err error
+			if i, err = strconv.ParseInt(strings.TrimPrefix(v.(string), "0o"), 8, 64); err != nil {
+				return &Error{"value_must_be_file_mode", f, nil}
+			}
+		} else {
+			i, _ = parseInt64(v)
+		}
+		if i < 0 || i&^a.m != 0 {
+			return &Error{"value_must_be_file_mode", f, nil}
+		}
+		return NoError
+	})).Validate(v, f)
+}
+
+func (a FileModeValidator) Traverse(v interface{}, f func(interface{}, Validator)) {
+	f(v, a)
+}
+
+func (a FileModeValidator) Walk(f func(Validator)) {
+	f(a)
+}
+
+func (a FileModeValidator) ConstraintTree() ConstraintNode {
+	if a.s {
+		return ConstraintNode{`typeof(v)==="string" && /^(0o?)?[0-7]+$/.test(v) && (parseInt(v.replace(/^0o/, ""), 8) & ~mask) === 0`, nil}
+	}
+	return ConstraintNode{`typeof(v)==="number" && (v % 1 === 0) && v >= 0 && (v & ~mask) === 0`, nil}
+}
+
+type PortValidator struct {
+	x, y int64
+}
+
+// 1 to 65535
+func Port() Validator {
+	return PortValidator{1, 65535}
+}
+
+// 0 to 65535, for fields where 0 means "pick any free port"
+func PortAllowingZero() Validator {
+	return PortValidator{0, 65535}
+}
+
+// 1 to 1023
+func PrivilegedPort() Validator {
+	return PortValidator{1, 1023}
+}
+
+// 49152 to 65535, the IANA dynamic range
+func EphemeralPort() Validator {
+	return PortValidator{49152, 65535}
+}
+
+func (a PortValidator) Validate(v interface{}, f []string) *Error {
+	return And(Int64(), lambda(func(v interface{}, f []string) *Error {
+		if i, _ := parseInt64(v); i < a.x || i > a.y {
+			return &Error{"value_must_be_port", f, map[string]int64{"min": a.x, "max": a.y}}
+		}
+		return NoError
+	})).Validate(v, f)
+}
+
+func (a PortValidator) Min() int64 {
+	return a.x
+}
+
+func (a PortValidator) Max() int64 {
+	return a.y
+}
+
+func (a PortValidator) Traverse(v interface{}, f func(interface{}, Validator)) {
+	f(v, a)
+}
+
+func (a PortValidator) Walk(f func(Validator)) {
+	f(a)
+}
+
+func (a PortValidator) ConstraintTree() ConstraintNode {
+	return ConstraintNode{`typeof(v)==="number" && (v % 1 === 0) && v >= min && v <= max`, nil}
+}
+
+// the value is a JSON pointer (RFC 6901), optionally written as a URI
+// fragment like "#/a/b", that must resolve within the document returned by r
+type InternalRefValidator struct {
+	r func() interface{}
+}
+
+func InternalRef(r func() interface{}) Validator {
+	return InternalRefValidator{r}
+}
+
+func (a InternalRefValidator) Validate(v interface{}, f []string) *Error {
+	return And(String(), lambda(func(v interface{}, f []string) *Error {
+		p, k := parsePointer(v.(string))
+		if !k {
+			return &Error{"value_must_be_json_pointer", f, nil}
+		}
+		if _, k := lookup(a.r(), p); !k {
+			return &Error{"ref_does_not_resolve", f, v}
+		}
+		return NoError
+	})).Validate(v, f)
+}
+
+func (a InternalRefValidator) Traverse(v interface{}, f func(interface{}, Validator)) {
+	f(v, a)
+}
+
+func (a InternalRefValidator) Walk(f func(Validator)) {
+	f(a)
+}
+
+func (a InternalRefValidator) ConstraintTree() ConstraintNode {
+	return ConstraintNode{`typeof(v)==="string" && resolve(<root>, v) !== undefined`, nil}
+}
+
+// compares with ==, which suits strings, booleans and null. numbers that
+// were computed rather than written out rarely hit a constant exactly; use
+// ExactlyNumber for them
+type ExactlyValidator struct {
+	j interface{}
+}
+
+func Exactly(j interface{}) Validator {
+	return ExactlyValidator{j}
+}
+
+func (a ExactlyValidator) Value() interface{} {
+	return a.j
+}
+
+func (a ExactlyValidator) Validate(v interface{}, f []string) *Error {
+	if v != a.j {
+		return &Error{"value_not_matched_exactly", f, nil}
+	}
+	return NoError
 }
 
-// see https://golang.org/pkg/regexp/syntax/
-func Regex(x, l string, i, m bool) Validator {
-	return RegexValidator{x, l, i, m}
+func (a ExactlyValidator) Traverse(v interface{}, f func(interface{}, Validator)) {
+	f(v, a)
 }
 
-func (a RegexValidator) Label() string {
-	return a.l
+func (a ExactlyValidator) Walk(f func(Validator)) {
+	f(a)
 }
 
-func (a RegexValidator) Expression() string {
-	return a.x
+func (a ExactlyValidator) ConstraintTree() ConstraintNode {
+	return ConstraintNode{`v === <value>`, nil}
 }
 
-func (a RegexValidator) Modifiers() (i, m bool) {
-	return a.i, a.m
+// an enum: v must deeply equal one of the given values, compared as
+// decoded by encoding/json, so numbers should be given as float64
+type OneOfValidator struct {
+	o []interface{}
 }
 
-func (a RegexValidator) Regex() *regexp.Regexp {
-	x := a.x
-	if a.i {
-		x = `(?i)` + x
-	}
-	if a.m {
-		x = `(?m)` + x
+func OneOf(o ...interface{}) Validator {
+	if len(o) == 0 {
+		panic("OneOf: no values")
 	}
-	return regexp.MustCompile(x)
+	return OneOfValidator{o}
 }
 
-func (a RegexValidator) Validate(v interface{}, f []string) *Error {
-	return And(String(), Lambda(func(v interface{}, f []string) *Error {
-		s := v.(string)
-		if a.Regex().MatchString(s) {
+func (a OneOfValidator) Values() []interface{} {
+	return a.o
+}
+
+func (a OneOfValidator) Validate(v interface{}, f []string) *Error {
+	for _, o := range a.o {
+		if reflect.DeepEqual(v, o) {
 			return NoError
 		}
-		return &Error{a.l, f, map[string]interface{}{
-			"regex": map[string]interface{}{
-				"expression": a.x,
-				"modifiers": map[string]bool{
-					"i": a.i,
-					"m": a.m,
-				},
-			},
-		}}
-	})).Validate(v, f)
+	}
+	return &Error{"value_not_one_of", f, a.o}
 }
 
-func (a RegexValidator) Traverse(v interface{}, f func(interface{}, Validator)) {
+func (a OneOfValidator) Traverse(v interface{}, f func(interface{}, Validator)) {
 	f(v, a)
 }
 
-func (a RegexValidator) ConstraintTree() ConstraintNode {
-	return ConstraintNode{`typeof(v)==="string" && /<regex>/.matches(v)`, nil}
+func (a OneOfValidator) Walk(f func(Validator)) {
+	f(a)
 }
 
-type LengthBetweenValidator struct {
-	x, y int
+func (a OneOfValidator) ConstraintTree() ConstraintNode {
+	return ConstraintNode{`[<values>].indexOf(v) > -1`, nil}
 }
 
-func LengthBetween(x, y int) Validator {
-	if y < x {
-		panic("LengthBetween: y < x")
-	}
-	return LengthBetweenValidator{x, y}
+// an array whose elements each deeply equal one of o, like the choices of
+// a multi-select. the first element that doesn't is in the error's context
+type SubsetOfValidator struct {
+	o []interface{}
 }
 
-func (a LengthBetweenValidator) Validate(v interface{}, f []string) *Error {
-	return And(Or(String(), Array(Anything())), Lambda(func(v interface{}, f []string) *Error {
-		l := -1
-		switch t := v.(type) {
-		case string:
-			l = utf8.RuneCountInString(t)
-		case []interface{}:
-			l = len(t)
-		}
-		if l < a.x || l > a.y {
-			if a.x == a.y {
-				return &Error{"value_must_have_length", f, a.x}
+func SubsetOf(o ...interface{}) Validator {
+	return SubsetOfValidator{o}
+}
+
+func (a SubsetOfValidator) Values() []interface{} {
+	return a.o
+}
+
+func (a SubsetOfValidator) Validate(v interface{}, f []string) *Error {
+	return And(Array(Anything()), lambda(func(v interface{}, f []string) *Error {
+	outer:
+		for i, u := range v.([]interface{}) {
+			for _, o := range a.o {
+				if reflect.DeepEqual(u, o) {
+					continue outer
+				}
 			}
-			return &Error{"value_must_have_length_between", f, map[string]int{"min": a.x, "max": a.y}}
+			return &Error{"array_contains_disallowed_value", appendField(f, strconv.Itoa(i)), u}
 		}
 		return NoError
 	})).Validate(v, f)
 }
 
-func (a LengthBetweenValidator) Min() int {
-	return a.x
+func (a SubsetOfValidator) Traverse(v interface{}, f func(interface{}, Validator)) {
+	f(v, a)
 }
 
-func (a LengthBetweenValidator) Max() int {
-	return a.y
+func (a SubsetOfValidator) Walk(f func(Validator)) {
+	f(a)
 }
 
-func (a LengthBetweenValidator) Traverse(v interface{}, f func(interface{}, Validator)) {
-	f(v, a)
+func (a SubsetOfValidator) ConstraintTree() ConstraintNode {
+	return ConstraintNode{`typeof(v)==="array" && v.every(e => [<values>].indexOf(e) > -1)`, nil}
 }
 
-func (a LengthBetweenValidator) ConstraintTree() ConstraintNode {
-	return ConstraintNode{`(typeof(v)==="string" && v.length >= min && v.length <= max) || (typeof(v)==="array" && v.length >= min && v.length <= max)`, nil}
+// a string enum matched with strings.EqualFold, for values like HTTP
+// methods. Canonical maps an input to the value as given here
+type OneOfFoldValidator struct {
+	o []string
 }
 
-func Length(x int) Validator {
-	return LengthBetween(x, x)
+func OneOfFold(o ...string) Validator {
+	if len(o) == 0 {
+		panic("OneOfFold: no values")
+	}
+	return OneOfFoldValidator{o}
 }
 
-type NumberBetweenValidator struct {
-	x, y float64
+func (a OneOfFoldValidator) Values() []string {
+	return a.o
 }
 
-func NumberBetween(x, y float64) Validator {
-	if y < x {
-		panic("NumberBetween: y < x")
+func (a OneOfFoldValidator) Canonical(s string) (string, bool) {
+	for _, o := range a.o {
+		if strings.EqualFold(s, o) {
+			return o, true
+		}
 	}
-	return NumberBetweenValidator{x, y}
+	return "", false
 }
 
-func (a NumberBetweenValidator) Validate(v interface{}, f []string) *Error {
-	return And(Number(), Lambda(func(v interface{}, f []string) *Error {
-		l := v.(float64)
-		if l < a.x || l > a.y {
-			return &Error{"value_must_have_value_between", f, map[string]float64{"min": a.x, "max": a.y}}
+func (a OneOfFoldValidator) Validate(v interface{}, f []string) *Error {
+	return And(String(), lambda(func(v interface{}, f []string) *Error {
+		if _, k := a.Canonical(v.(string)); !k {
+			return &Error{"value_not_in_enum", f, a.o}
 		}
 		return NoError
 	})).Validate(v, f)
 }
 
-func (a NumberBetweenValidator) Traverse(v interface{}, f func(interface{}, Validator)) {
+func (a OneOfFoldValidator) Traverse(v interface{}, f func(interface{}, Validator)) {
 	f(v, a)
 }
 
-func (a NumberBetweenValidator) ConstraintTree() ConstraintNode {
-	return ConstraintNode{`typeof(v)==="number" && v >= min && v =< max`, nil}
+func (a OneOfFoldValidator) Walk(f func(Validator)) {
+	f(a)
 }
 
-type WholeNumberValidator struct{}
-
-func WholeNumber() Validator {
-	return WholeNumberValidator{}
+func (a OneOfFoldValidator) ConstraintTree() ConstraintNode {
+	return ConstraintNode{`typeof(v)==="string" && [<values>].map(function(o){ return o.toLowerCase() }).indexOf(v.toLowerCase()) > -1`, nil}
 }
 
-func (a WholeNumberValidator) Validate(v interface{}, f []string) *Error {
-	return And(Number(), Lambda(func(v interface{}, f []string) *Error {
-		n := v.(float64)
-		_, r := math.Modf(n)
-		if r != 0 {
-			return &Error{"value_must_be_whole_number", f, nil}
-		}
-		return NoError
-	})).Validate(v, f)
+// a number within e of n
+type ExactlyNumberValidator struct {
+	n, e float64
 }
 
-func (a WholeNumberValidator) Traverse(v interface{}, f func(interface{}, Validator)) {
-	f(v, a)
+func ExactlyNumber(n, e float64) Validator {
+	if e < 0 {
+		panic("ExactlyNumber: e < 0")
+	}
+	return ExactlyNumberValidator{n, e}
 }
 
-func (a WholeNumberValidator) ConstraintTree() ConstraintNode {
-	return ConstraintNode{`typeof(v)==="number" && (v % 1 === 0)`, nil}
+func (a ExactlyNumberValidator) Value() float64 {
+	return a.n
 }
 
-type WholeNumberBetweenValidator struct {
-	x, y int
+func (a ExactlyNumberValidator) Epsilon() float64 {
+	return a.e
 }
 
-func WholeNumberBetween(x, y int) Validator {
-	if y < x {
-		panic("WholeNumberBetween: y < x")
-	}
-	return WholeNumberBetweenValidator{x, y}
+func (a ExactlyNumberValidator) Validate(v interface{}, f []string) *Error {
+	return And(Number(), lambda(func(v interface{}, f []string) *Error {
+		if !(math.Abs(v.(float64)-a.n) <= a.e) {
+			return &Error{"value_not_matched_exactly", f, a.n}
+		}
+		return NoError
+	})).Validate(v, f)
 }
 
-func (a WholeNumberBetweenValidator) Validate(v interface{}, f []string) *Error {
-	return And(WholeNumber(), NumberBetween(float64(a.x), float64(a.y))).Validate(v, f)
+func (a ExactlyNumberValidator) Traverse(v interface{}, f func(interface{}, Validator)) {
+	f(v, a)
 }
 
-func (a WholeNumberBetweenValidator) Traverse(v interface{}, f func(interface{}, Validator)) {
-	f(v, a)
+func (a ExactlyNumberValidator) Walk(f func(Validator)) {
+	f(a)
 }
 
-func (a WholeNumberBetweenValidator) ConstraintTree() ConstraintNode {
-	return ConstraintNode{`typeof(v)==="number" && (v % 1 === 0) && v >= min && v <= max`, nil}
+func (a ExactlyNumberValidator) ConstraintTree() ConstraintNode {
+	return ConstraintNode{`typeof(v)==="number" && Math.abs(v - <value>) <= <epsilon>`, nil}
 }
 
-type ExactlyValidator struct {
-	j interface{}
+// compares strings with strings.EqualFold; Exactly stays case-sensitive
+type ExactlyFoldValidator struct {
+	s string
 }
 
-func Exactly(j interface{}) Validator {
-	return ExactlyValidator{j}
+func ExactlyFold(s string) Validator {
+	return ExactlyFoldValidator{s}
 }
 
-func (a ExactlyValidator) Value() interface{} {
-	return a.j
+func (a ExactlyFoldValidator) Value() string {
+	return a.s
 }
 
-func (a ExactlyValidator) Validate(v interface{}, f []string) *Error {
-	if v != a.j {
-		return &Error{"value_not_matched_exactly", f, nil}
+func (a ExactlyFoldValidator) Validate(v interface{}, f []string) *Error {
+	if s, k := v.(string); !k || !strings.EqualFold(s, a.s) {
+		return &Error{"value_not_matched_exactly", f, a.s}
 	}
 	return NoError
 }
 
-func (a ExactlyValidator) Traverse(v interface{}, f func(interface{}, Validator)) {
+func (a ExactlyFoldValidator) Traverse(v interface{}, f func(interface{}, Validator)) {
 	f(v, a)
 }
 
-func (a ExactlyValidator) ConstraintTree() ConstraintNode {
-	return ConstraintNode{`v === <value>`, nil}
+func (a ExactlyFoldValidator) Walk(f func(Validator)) {
+	f(a)
+}
+
+func (a ExactlyFoldValidator) ConstraintTree() ConstraintNode {
+	return ConstraintNode{`v.toLowerCase() === <value>.toLowerCase()`, nil}
 }
 
 // here be dragons! change only if you know exactly what you're doing
@@ -653,7 +5604,6 @@ func (a ExactlyValidator) ConstraintTree() ConstraintNode {
 // NOT thread safe
 type RecursiveValidator struct {
 	v Validator
-	l bool
 }
 
 func Recursion(f func(Validator) Validator) Validator {
@@ -667,7 +5617,11 @@ func (r *RecursiveValidator) Validator() Validator {
 }
 
 func (r *RecursiveValidator) Validate(v interface{}, f []string) *Error {
-	return r.v.Validate(v, f)
+	return r.ValidateCtx(context.Background(), v, f)
+}
+
+func (r *RecursiveValidator) ValidateCtx(ctx context.Context, v interface{}, f []string) *Error {
+	return ValidateCtx(ctx, r.v, v, f)
 }
 
 func (r *RecursiveValidator) Define(v Validator) {
@@ -678,15 +5632,340 @@ func (r *RecursiveValidator) Traverse(v interface{}, f func(interface{}, Validat
 	r.v.Traverse(v, f)
 }
 
+func (r *RecursiveValidator) TraversePath(v interface{}, p []string, f func(interface{}, []string, Validator)) {
+	TraversePath(r.v, v, p, f)
+}
+
+func (r *RecursiveValidator) Walk(f func(Validator)) {
+	r.WalkVisited(f, Visited{})
+}
+
+func (r *RecursiveValidator) WalkVisited(f func(Validator), w Visited) {
+	f(r)
+	if w[r] {
+		return
+	}
+	w[r] = true
+	WalkVisited(r.v, f, w)
+	delete(w, r)
+}
+
 func (r *RecursiveValidator) ConstraintTree() ConstraintNode {
-	if r.l {
+	return r.ConstraintTreeVisited(Visited{})
+}
+
+func (r *RecursiveValidator) ConstraintTreeVisited(w Visited) ConstraintNode {
+	if w[r] {
+		return ConstraintNode{`<recursion>`, nil}
+	}
+	w[r] = true
+	c := ConstraintTreeVisited(r.v, w)
+	delete(w, r)
+	return c
+}
+
+// resolves f on first use, so package-level validators can reference each
+// other regardless of initialization order. safe for concurrent use
+type LazyValidator struct {
+	f func() Validator
+	o sync.Once
+	v Validator
+}
+
+func Lazy(f func() Validator) Validator {
+	return &LazyValidator{f: f}
+}
+
+func (a *LazyValidator) Validator() Validator {
+	a.o.Do(func() {
+		a.v = a.f()
+	})
+	return a.v
+}
+
+func (a *LazyValidator) Validate(v interface{}, f []string) *Error {
+	return a.ValidateCtx(context.Background(), v, f)
+}
+
+func (a *LazyValidator) ValidateCtx(ctx context.Context, v interface{}, f []string) *Error {
+	return ValidateCtx(ctx, a.Validator(), v, f)
+}
+
+func (a *LazyValidator) Traverse(v interface{}, f func(interface{}, Validator)) {
+	a.Validator().Traverse(v, f)
+}
+
+func (a *LazyValidator) TraversePath(v interface{}, p []string, f func(interface{}, []string, Validator)) {
+	TraversePath(a.Validator(), v, p, f)
+}
+
+func (a *LazyValidator) Walk(f func(Validator)) {
+	a.WalkVisited(f, Visited{})
+}
+
+func (a *LazyValidator) WalkVisited(f func(Validator), w Visited) {
+	f(a)
+	if w[a] {
+		return
+	}
+	w[a] = true
+	WalkVisited(a.Validator(), f, w)
+	delete(w, a)
+}
+
+func (a *LazyValidator) ConstraintTree() ConstraintNode {
+	return a.ConstraintTreeVisited(Visited{})
+}
+
+func (a *LazyValidator) ConstraintTreeVisited(w Visited) ConstraintNode {
+	if w[a] {
 		return ConstraintNode{`<recursion>`, nil}
 	}
-	r.l = true
-	c := r.v.ConstraintTree()
-	r.l = false
+	w[a] = true
+	c := ConstraintTreeVisited(a.Validator(), w)
+	delete(w, a)
 	return c
+}
+
+// false unless v is a whole number within int64
+func parseInt64(v interface{}) (int64, bool) {
+	switch t := v.(type) {
+	case json.Number:
+		i, err := strconv.ParseInt(string(t), 10, 64)
+		if err == nil {
+			return i, true
+		}
+		if err.(*strconv.NumError).Err == strconv.ErrRange {
+			return 0, false
+		}
+		n, err := t.Float64()
+		if err != nil {
+			return 0, false
+		}
+		return parseInt64(n)
+	case float64:
+		if _, r := math.Modf(t); r != 0 {
+			return 0, false
+		}
+		if t < math.MinInt64 || t >= math.MaxInt64 {
+			return 0, false
+		}
+		return int64(t), true
+	}
+	return 0, false
+}
+
+func parsePointer(s string) ([]string, bool) {
+	s = strings.TrimPrefix(s, "#")
+	if s == "" {
+		return []string{}, true
+	}
+	if s[0] != '/' {
+		return nil, false
+	}
+	p := strings.Split(s[1:], "/")
+	for i, t := range p {
+		for j := 0; j < len(t); j++ {
+			if t[j] == '~' && (j == len(t)-1 || (t[j+1] != '0' && t[j+1] != '1')) {
+				return nil, false
+			}
+		}
+		p[i] = strings.Replace(strings.Replace(t, "~1", "/", -1), "~0", "~", -1)
+	}
+	return p, true
+}
+
+// navigates objects by key and arrays by index
+func lookup(v interface{}, p []string) (interface{}, bool) {
+	for _, k := range p {
+		switch t := v.(type) {
+		case map[string]interface{}:
+			u, x := t[k]
+			if !x {
+				return nil, false
+			}
+			v = u
+		case []interface{}:
+			i, err := strconv.Atoi(k)
+			if err != nil || i < 0 || i >= len(t) || strconv.Itoa(i) != k {
+				return nil, false
+			}
+			v = t[i]
+		default:
+			return nil, false
+		}
+	}
+	return v, true
+}
+
+func presentKeys(o map[string]interface{}, ks []string) []string {
+	p := make([]string, 0, len(ks))
+	for _, k := range ks {
+		if _, x := o[k]; x {
+			p = append(p, k)
+		}
+	}
+	return p
+}
+
+// resolves the validator with id through r on first use and caches it.
+// failed resolutions aren't cached, the next use tries again. r runs without
+// the lock held so it may use the reference itself; concurrent first uses may
+// each call r, and the first success is kept. Walk and ConstraintTree stop at
+// the reference
+type RemoteRefValidator struct {
+	i string
+	r func(string) (Validator, error)
+	m sync.Mutex
+	v Validator
+}
+
+func RemoteRef(i string, r func(string) (Validator, error)) Validator {
+	return &RemoteRefValidator{i: i, r: r}
+}
+
+func (a *RemoteRefValidator) ID() string {
+	return a.i
+}
+
+func (a *RemoteRefValidator) Resolve() (Validator, error) {
+	a.m.Lock()
+	v := a.v
+	a.m.Unlock()
+	if v != nil {
+		return v, nil
+	}
+	v, err := a.r(a.i)
+	if err != nil {
+		return nil, err
+	}
+	a.m.Lock()
+	defer a.m.Unlock()
+	if a.v == nil {
+		a.v = v
+	}
+	return a.v, nil
+}
+
+func (a *RemoteRefValidator) Validate(v interface{}, f []string) *Error {
+	return a.ValidateCtx(context.Background(), v, f)
+}
+
+func (a *RemoteRefValidator) ValidateCtx(ctx context.Context, v interface{}, f []string) *Error {
+	r, err := a.Resolve()
+	if err != nil {
+		return &Error{"ref_resolution_failed", f, map[string]string{"id": a.i, "error": err.Error()}}
+	}
+	return ValidateCtx(ctx, r, v, f)
+}
+
+func (a *RemoteRefValidator) Traverse(v interface{}, f func(interface{}, Validator)) {
+	r, err := a.Resolve()
+	if err != nil {
+		f(v, a)
+		return
+	}
+	r.Traverse(v, f)
+}
+
+func (a *RemoteRefValidator) TraversePath(v interface{}, p []string, f func(interface{}, []string, Validator)) {
+	r, err := a.Resolve()
+	if err != nil {
+		f(v, p, a)
+		return
+	}
+	TraversePath(r, v, p, f)
+}
+
+func (a *RemoteRefValidator) Walk(f func(Validator)) {
+	f(a)
+}
+
+func (a *RemoteRefValidator) ConstraintTree() ConstraintNode {
+	return ConstraintNode{`<remote:` + a.i + `>`, nil}
+}
+
+// named validators, referenced with Ref. the zero value is ready to use
+type Registry struct {
+	m sync.RWMutex
+	d map[string]Validator
+}
+
+func NewRegistry() *Registry {
+	return &Registry{}
+}
+
+func (r *Registry) Register(n string, v Validator) {
+	r.m.Lock()
+	defer r.m.Unlock()
+	if r.d == nil {
+		r.d = make(map[string]Validator)
+	}
+	r.d[n] = v
+}
+
+func (r *Registry) Lookup(n string) (Validator, bool) {
+	r.m.RLock()
+	defer r.m.RUnlock()
+	v, k := r.d[n]
+	return v, k
+}
+
+// looks n up on every use, so it may be registered after the reference is
+// made, including from within its own definition
+func (r *Registry) Ref(n string) Validator {
+	return RefValidator{r, n}
+}
+
+type RefValidator struct {
+	r *Registry
+	n string
+}
+
+func (a RefValidator) Name() string {
+	return a.n
+}
+
+func (a RefValidator) Registry() *Registry {
+	return a.r
+}
+
+func (a RefValidator) Validate(v interface{}, f []string) *Error {
+	return a.ValidateCtx(context.Background(), v, f)
+}
+
+func (a RefValidator) ValidateCtx(ctx context.Context, v interface{}, f []string) *Error {
+	r, k := a.r.Lookup(a.n)
+	if !k {
+		return &Error{"unresolved_ref", f, a.n}
+	}
+	return ValidateCtx(ctx, r, v, f)
+}
+
+func (a RefValidator) Traverse(v interface{}, f func(interface{}, Validator)) {
+	r, k := a.r.Lookup(a.n)
+	if !k {
+		f(v, a)
+		return
+	}
+	r.Traverse(v, f)
+}
+
+func (a RefValidator) TraversePath(v interface{}, p []string, f func(interface{}, []string, Validator)) {
+	r, k := a.r.Lookup(a.n)
+	if !k {
+		f(v, p, a)
+		return
+	}
+	TraversePath(r, v, p, f)
+}
+
+func (a RefValidator) Walk(f func(Validator)) {
+	f(a)
+}
 
+func (a RefValidator) ConstraintTree() ConstraintNode {
+	return ConstraintNode{`<ref:` + a.n + `>`, nil}
 }
 
 func uniqueErrors(es []*Error) []*Error {
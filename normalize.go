@@ -0,0 +1,70 @@
+package jval
+
+import (
+	"encoding/json"
+	"reflect"
+)
+
+// Normalize converts a value built in Go into the shape encoding/json would
+// decode it to: numbers of any kind become float64, slices and arrays
+// []interface{}, maps with string keys map[string]interface{}, named
+// strings and bools their plain types. json.Number is kept, as are values
+// without a JSON counterpart
+func Normalize(v interface{}) interface{} {
+	switch t := v.(type) {
+	case nil, json.Number, string, bool, float64:
+		return v
+	case map[string]interface{}:
+		o := make(map[string]interface{}, len(t))
+		for k, u := range t {
+			o[k] = Normalize(u)
+		}
+		return o
+	case []interface{}:
+		o := make([]interface{}, len(t))
+		for i, u := range t {
+			o[i] = Normalize(u)
+		}
+		return o
+	}
+	r := reflect.ValueOf(v)
+	switch r.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(r.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return float64(r.Uint())
+	case reflect.Float32, reflect.Float64:
+		return r.Float()
+	case reflect.String:
+		return r.String()
+	case reflect.Bool:
+		return r.Bool()
+	case reflect.Slice, reflect.Array:
+		if r.Kind() == reflect.Slice && r.IsNil() {
+			return nil
+		}
+		o := make([]interface{}, r.Len())
+		for i := range o {
+			o[i] = Normalize(r.Index(i).Interface())
+		}
+		return o
+	case reflect.Map:
+		if r.Type().Key().Kind() != reflect.String {
+			return v
+		}
+		if r.IsNil() {
+			return nil
+		}
+		o := make(map[string]interface{}, r.Len())
+		for _, k := range r.MapKeys() {
+			o[k.String()] = Normalize(r.MapIndex(k).Interface())
+		}
+		return o
+	}
+	return v
+}
+
+// validates a value built in Go rather than decoded from JSON, see Normalize
+func ValidateGo(value interface{}, v Validator) *Error {
+	return v.Validate(Normalize(value), []string{})
+}
@@ -0,0 +1,88 @@
+package jval
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+type goName string
+
+func TestNormalize(t *testing.T) {
+	var nilSlice []int
+	var nilMap map[string]int
+	cs := []struct {
+		name string
+		in   interface{}
+		want interface{}
+	}{
+		{"nil", nil, nil},
+		{"int", 3, 3.0},
+		{"int64", int64(-1) << 40, float64(int64(-1) << 40)},
+		{"int8", int8(-8), -8.0},
+		{"uint16", uint16(65535), 65535.0},
+		{"float32", float32(0.5), 0.5},
+		{"float64", 1.25, 1.25},
+		{"json number", json.Number("12"), json.Number("12")},
+		{"named string", goName("x"), "x"},
+		{"bool", true, true},
+		{"int slice", []int{1, 2}, []interface{}{1.0, 2.0}},
+		{"array", [2]string{"a", "b"}, []interface{}{"a", "b"}},
+		{"nil slice", nilSlice, nil},
+		{"typed map", map[string]int{"a": 1}, map[string]interface{}{"a": 1.0}},
+		{"nil map", nilMap, nil},
+		{"non-string keys", map[int]int{1: 1}, map[int]int{1: 1}},
+		{"nested", map[string]interface{}{"a": []interface{}{int64(1), map[string]float32{"b": 2}}},
+			map[string]interface{}{"a": []interface{}{1.0, map[string]interface{}{"b": 2.0}}}},
+	}
+	for _, c := range cs {
+		t.Run(c.name, func(t *testing.T) {
+			if got := Normalize(c.in); !reflect.DeepEqual(got, c.want) {
+				t.Fatalf("got %#v, want %#v", got, c.want)
+			}
+		})
+	}
+}
+
+func TestValidateGo(t *testing.T) {
+	v := Object(map[string]Validator{
+		"id":    Number(),
+		"score": NumberBetween(0, 1),
+		"tags":  Array(String()),
+	})
+	cs := []struct {
+		name  string
+		value interface{}
+		label string
+	}{
+		{"int", map[string]interface{}{"id": 1, "score": float32(0.5), "tags": []string{"a"}}, ""},
+		{"int64", map[string]interface{}{"id": int64(1) << 50, "score": 0, "tags": []string{}}, ""},
+		{"float32 out of range", map[string]interface{}{"id": 1, "score": float32(1.5), "tags": []string{}}, "value_must_have_value_between"},
+		{"wrong type", map[string]interface{}{"id": "1", "score": 0, "tags": []string{}}, "value_must_be_number"},
+	}
+	for _, c := range cs {
+		t.Run(c.name, func(t *testing.T) {
+			e := ValidateGo(c.value, v)
+			if c.label == "" {
+				if e != nil {
+					t.Fatalf("unexpected error %v", e)
+				}
+				return
+			}
+			if e == nil || !hasLabel(e, c.label) {
+				t.Fatalf("got %v, want %s", e, c.label)
+			}
+		})
+	}
+	if e := v.Validate(map[string]interface{}{"id": 1, "score": 0, "tags": []interface{}{}}, []string{}); e == nil {
+		t.Fatal("raw int accepted without normalizing")
+	}
+}
+
+func BenchmarkNormalize(b *testing.B) {
+	x := map[string]interface{}{"id": 1, "score": float32(0.5), "tags": []string{"a", "b", "c"}}
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		Normalize(x)
+	}
+}
@@ -0,0 +1,263 @@
+package jval
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// ToOpenAPISchema renders v as an OpenAPI 3.0 Schema Object. null is
+// expressed as "nullable" on the other alternative, so a bare Null is an
+// error. Discriminator becomes oneOf with a discriminator mapping and needs
+// every alternative to be a Ref, which becomes a $ref into
+// #/components/schemas. the annotations description, example, deprecated,
+// title and default are copied into the schema, any other gets an "x-"
+// prefix. validators without an OpenAPI counterpart, like Lambda or
+// Recursion, are an error
+func ToOpenAPISchema(v Validator) (map[string]interface{}, error) {
+	return toOpenAPI(v, "#")
+}
+
+func toOpenAPI(v Validator, p string) (map[string]interface{}, error) {
+	switch a := v.(type) {
+	case AnythingValidator:
+		return map[string]interface{}{}, nil
+	case StringValidator:
+		return map[string]interface{}{"type": "string"}, nil
+	case NumberValidator, FiniteNumberValidator:
+		return map[string]interface{}{"type": "number"}, nil
+	case BooleanValidator:
+		return map[string]interface{}{"type": "boolean"}, nil
+	case NullValidator:
+		return nil, fmt.Errorf("openapi: %s: null can only be expressed as nullable", p)
+	case WholeNumberValidator:
+		return map[string]interface{}{"type": "integer"}, nil
+	case WholeNumberBetweenValidator:
+		return map[string]interface{}{"type": "integer", "minimum": a.x, "maximum": a.y}, nil
+	case IntBetweenValidator:
+		return map[string]interface{}{"type": "integer", "minimum": a.x, "maximum": a.y}, nil
+	case EnumIntValidator:
+		cs := make([]int, 0, len(a.c))
+		for c, _ := range a.c {
+			cs = append(cs, c)
+		}
+		sort.Ints(cs)
+		return map[string]interface{}{"type": "integer", "enum": cs}, nil
+	case IntWidthValidator:
+		if (a.b == 32 || a.b == 64) && !a.u {
+			return map[string]interface{}{"type": "integer", "format": fmt.Sprintf("int%d", a.b)}, nil
+		}
+		return map[string]interface{}{"type": "integer", "minimum": a.Min(), "maximum": a.Max()}, nil
+	case Int64BetweenValidator:
+		return map[string]interface{}{"type": "integer", "format": "int64", "minimum": a.x, "maximum": a.y}, nil
+	case NumberBetweenValidator:
+		return map[string]interface{}{"type": "number", "minimum": a.x, "maximum": a.y}, nil
+	case ProportionValidator:
+		return map[string]interface{}{"type": "number", "minimum": 0, "maximum": a.y}, nil
+	case NumberMinValidator:
+		return map[string]interface{}{"type": "number", "minimum": a.x}, nil
+	case NumberMaxValidator:
+		return map[string]interface{}{"type": "number", "maximum": a.y}, nil
+	case MultipleOfValidator:
+		return map[string]interface{}{"type": "number", "multipleOf": a.n}, nil
+	case LengthBetweenValidator:
+		return map[string]interface{}{"minLength": a.x, "maxLength": a.y, "minItems": a.x, "maxItems": a.y}, nil
+	case LengthMinValidator:
+		return map[string]interface{}{"minLength": a.x, "minItems": a.x}, nil
+	case LengthMaxValidator:
+		return map[string]interface{}{"maxLength": a.y, "maxItems": a.y}, nil
+	case KeyCountBetweenValidator:
+		if a.y == -1 {
+			return map[string]interface{}{"type": "object", "minProperties": a.x}, nil
+		}
+		return map[string]interface{}{"type": "object", "minProperties": a.x, "maxProperties": a.y}, nil
+	case RegexValidator:
+		if a.i || a.m {
+			return nil, fmt.Errorf("openapi: %s: regex modifiers can't be expressed", p)
+		}
+		return map[string]interface{}{"type": "string", "pattern": a.x}, nil
+	case HexColorValidator:
+		if a.a {
+			return map[string]interface{}{"type": "string", "pattern": "^#([0-9a-fA-F]{3}|[0-9a-fA-F]{6}|[0-9a-fA-F]{8})$"}, nil
+		}
+		return map[string]interface{}{"type": "string", "pattern": "^#([0-9a-fA-F]{3}|[0-9a-fA-F]{6})$"}, nil
+	case URLValidator:
+		return map[string]interface{}{"type": "string", "format": "uri"}, nil
+	case HostnameValidator:
+		return map[string]interface{}{"type": "string", "format": "hostname"}, nil
+	case IPValidator:
+		if a.v == 0 {
+			return map[string]interface{}{"type": "string"}, nil
+		}
+		return map[string]interface{}{"type": "string", "format": fmt.Sprintf("ipv%d", a.v)}, nil
+	case Base64Validator:
+		if a.u || a.r {
+			return map[string]interface{}{"type": "string"}, nil
+		}
+		return map[string]interface{}{"type": "string", "format": "byte"}, nil
+	case ExactlyValidator:
+		return map[string]interface{}{"enum": []interface{}{a.j}}, nil
+	case OneOfValidator:
+		return map[string]interface{}{"enum": a.o}, nil
+	case SubsetOfValidator:
+		return map[string]interface{}{"type": "array", "items": map[string]interface{}{"enum": a.o}}, nil
+	case ObjectValidator:
+		ps := make(map[string]interface{}, len(a))
+		rs := make([]string, 0, len(a))
+		for k, b := range a {
+			if !isOptional(b) {
+				rs = append(rs, k)
+			}
+			s, err := toOpenAPI(b, p+"/properties/"+k)
+			if err != nil {
+				return nil, err
+			}
+			ps[k] = s
+		}
+		s := map[string]interface{}{"type": "object", "properties": ps, "additionalProperties": false}
+		if len(rs) > 0 {
+			sort.Strings(rs)
+			s["required"] = rs
+		}
+		return s, nil
+	case MapValidator:
+		e, err := toOpenAPI(a.e, p+"/additionalProperties")
+		if err != nil {
+			return nil, err
+		}
+		return map[string]interface{}{"type": "object", "additionalProperties": e}, nil
+	case ArrayValidator:
+		e, err := toOpenAPI(a.e, p+"/items")
+		if err != nil {
+			return nil, err
+		}
+		return map[string]interface{}{"type": "array", "items": e}, nil
+	case AndValidator:
+		return openAPIAllOf(a, p)
+	case OrValidator:
+		return openAPIAnyOf(a, p)
+	case BestMatchOrValidator:
+		return openAPIAnyOf(a, p)
+	case NullableValidator:
+		return openAPIAnyOf([]Validator{a.e, Null()}, p)
+	case OptionalValidator:
+		return toOpenAPI(a.e, p)
+	case DiscriminatorValidator:
+		ks := make([]string, 0, len(a.d))
+		for k, _ := range a.d {
+			ks = append(ks, k)
+		}
+		sort.Strings(ks)
+		os := make([]interface{}, len(ks))
+		ms := make(map[string]interface{}, len(ks))
+		for i, k := range ks {
+			r, x := a.d[k].(RefValidator)
+			if !x {
+				return nil, fmt.Errorf("openapi: %s/oneOf/%d: discriminator alternatives must be refs", p, i)
+			}
+			s, err := toOpenAPI(r, fmt.Sprintf("%s/oneOf/%d", p, i))
+			if err != nil {
+				return nil, err
+			}
+			os[i] = s
+			ms[k] = s["$ref"]
+		}
+		return map[string]interface{}{"oneOf": os, "discriminator": map[string]interface{}{"propertyName": a.k, "mapping": ms}}, nil
+	case AnnotatedValidator:
+		s, err := toOpenAPI(a.e, p)
+		if err != nil {
+			return nil, err
+		}
+		for k, u := range a.m {
+			s[openAPIAnnotation(k)] = u
+		}
+		return s, nil
+	case RefValidator:
+		return map[string]interface{}{"$ref": "#/components/schemas/" + a.n}, nil
+	case TimeBudgetValidator:
+		return toOpenAPI(a.e, p)
+	case MaxDepthValidator:
+		return toOpenAPI(a.e, p)
+	case PrefixFieldsValidator:
+		return toOpenAPI(a.e, p)
+	case RecoverValidator:
+		return toOpenAPI(a.e, p)
+	case TeeValidator:
+		return toOpenAPI(a.e, p)
+	case *MemoizedValidator:
+		return toOpenAPI(a.e, p)
+	case *Coverage:
+		return toOpenAPI(a.v, p)
+	case coverageProbe:
+		return toOpenAPI(a.e, p)
+	}
+	return nil, fmt.Errorf("openapi: %s: %T can't be expressed", p, v)
+}
+
+// merges the schemas of vs into one if their keywords don't clash, and
+// falls back to allOf if they do
+func openAPIAllOf(vs []Validator, p string) (map[string]interface{}, error) {
+	m := make(map[string]interface{})
+	as := make([]interface{}, len(vs))
+	c := false
+	for i, v := range vs {
+		s, err := toOpenAPI(v, fmt.Sprintf("%s/allOf/%d", p, i))
+		if err != nil {
+			return nil, err
+		}
+		for k, u := range s {
+			if w, x := m[k]; x && (k != "type" || w != u) {
+				c = true
+			}
+			m[k] = u
+		}
+		as[i] = s
+	}
+	if c {
+		return map[string]interface{}{"allOf": as}, nil
+	}
+	return m, nil
+}
+
+// alternatives that are null make the others nullable
+func openAPIAnyOf(vs []Validator, p string) (map[string]interface{}, error) {
+	n := false
+	as := make([]interface{}, 0, len(vs))
+	for i, v := range vs {
+		if _, x := v.(NullValidator); x {
+			n = true
+			continue
+		}
+		s, err := toOpenAPI(v, fmt.Sprintf("%s/anyOf/%d", p, i))
+		if err != nil {
+			return nil, err
+		}
+		as = append(as, s)
+	}
+	var s map[string]interface{}
+	switch len(as) {
+	case 0:
+		return nil, fmt.Errorf("openapi: %s: null can only be expressed as nullable", p)
+	case 1:
+		s = as[0].(map[string]interface{})
+	default:
+		s = map[string]interface{}{"anyOf": as}
+	}
+	if n {
+		s["nullable"] = true
+	}
+	return s, nil
+}
+
+// annotations the Schema Object knows keep their name, others become
+// extensions
+func openAPIAnnotation(k string) string {
+	switch k {
+	case "description", "example", "deprecated", "title", "default":
+		return k
+	}
+	if strings.HasPrefix(k, "x-") {
+		return k
+	}
+	return "x-" + k
+}
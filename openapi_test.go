@@ -0,0 +1,148 @@
+package jval
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+const openAPIGolden = `{
+  "additionalProperties": false,
+  "properties": {
+    "id": {
+      "format": "int64",
+      "type": "integer"
+    },
+    "nickname": {
+      "nullable": true,
+      "type": "string"
+    },
+    "note": {
+      "description": "free text",
+      "example": "hi",
+      "nullable": true,
+      "type": "string",
+      "x-internal": true
+    },
+    "pet": {
+      "discriminator": {
+        "mapping": {
+          "cat": "#/components/schemas/Cat",
+          "dog": "#/components/schemas/Dog"
+        },
+        "propertyName": "kind"
+      },
+      "oneOf": [
+        {
+          "$ref": "#/components/schemas/Cat"
+        },
+        {
+          "$ref": "#/components/schemas/Dog"
+        }
+      ]
+    },
+    "tags": {
+      "items": {
+        "type": "string"
+      },
+      "type": "array"
+    }
+  },
+  "required": [
+    "id",
+    "nickname",
+    "pet"
+  ],
+  "type": "object"
+}`
+
+func TestToOpenAPISchemaGolden(t *testing.T) {
+	r := NewRegistry()
+	v := Object(map[string]Validator{
+		"id":       Int64(),
+		"nickname": Nullable(String()),
+		"note": Annotate(Optional(Or(String(), Null())), map[string]interface{}{
+			"description": "free text", "example": "hi", "internal": true,
+		}),
+		"tags": Optional(Array(String())),
+		"pet":  Discriminator("kind", map[string]Validator{"cat": r.Ref("Cat"), "dog": r.Ref("Dog")}),
+	})
+	s, err := ToOpenAPISchema(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	bs, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(bs) != openAPIGolden {
+		t.Fatalf("got\n%s\nwant\n%s", bs, openAPIGolden)
+	}
+}
+
+func TestToOpenAPISchemaAnnotations(t *testing.T) {
+	cs := []struct {
+		name string
+		key  string
+		want string
+	}{
+		{"description", "description", "description"},
+		{"example", "example", "example"},
+		{"deprecated", "deprecated", "deprecated"},
+		{"title", "title", "title"},
+		{"default", "default", "default"},
+		{"unknown", "owner", "x-owner"},
+		{"schema keyword", "minimum", "x-minimum"},
+		{"extension", "x-owner", "x-owner"},
+	}
+	for _, c := range cs {
+		t.Run(c.name, func(t *testing.T) {
+			s, err := ToOpenAPISchema(Annotate(Number(), map[string]interface{}{c.key: 1}))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, x := s[c.want]; !x || len(s) != 2 {
+				t.Fatalf("got %v, want %s", s, c.want)
+			}
+		})
+	}
+}
+
+func TestToOpenAPISchemaErrors(t *testing.T) {
+	r := NewRegistry()
+	cs := []struct {
+		name string
+		v    Validator
+		path string
+	}{
+		{"bare null", Null(), "#"},
+		{"null only", Or(Null(), Null()), "#"},
+		{"null field", Object(map[string]Validator{"a": Null()}), "#/properties/a"},
+		{"inline discriminator", Discriminator("kind", map[string]Validator{
+			"a": r.Ref("A"), "b": Object(map[string]Validator{"kind": String()}),
+		}), "#/oneOf/1"},
+		{"lambda", Lambda(func(interface{}, []string) *Error { return NoError }), "#"},
+	}
+	for _, c := range cs {
+		t.Run(c.name, func(t *testing.T) {
+			_, err := ToOpenAPISchema(c.v)
+			if err == nil || !strings.Contains(err.Error(), c.path+":") {
+				t.Fatalf("got %v, want an error at %s", err, c.path)
+			}
+		})
+	}
+}
+
+func BenchmarkToOpenAPISchema(b *testing.B) {
+	r := NewRegistry()
+	v := Object(map[string]Validator{
+		"id":       Int64(),
+		"nickname": Nullable(String()),
+		"tags":     Optional(Array(String())),
+		"pet":      Discriminator("kind", map[string]Validator{"cat": r.Ref("Cat"), "dog": r.Ref("Dog")}),
+	})
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		ToOpenAPISchema(v)
+	}
+}
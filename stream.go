@@ -0,0 +1,178 @@
+package jval
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"strconv"
+)
+
+// decodes a single JSON value from r and validates it
+func ValidateStream(r io.Reader, v Validator) *Error {
+	var x interface{}
+	if err := json.NewDecoder(r).Decode(&x); err != nil {
+		return &Error{"invalid_json", []string{}, err.Error()}
+	}
+	return v.Validate(x, []string{})
+}
+
+// like ValidateStream, but gives up with "payload_too_large" once more than
+// n bytes have been read from r
+func ValidateStreamLimit(r io.Reader, v Validator, n int64) *Error {
+	l := &io.LimitedReader{R: r, N: n + 1}
+	var x interface{}
+	err := json.NewDecoder(l).Decode(&x)
+	if l.N == 0 {
+		return &Error{"payload_too_large", []string{}, n}
+	}
+	if err != nil {
+		return &Error{"invalid_json", []string{}, err.Error()}
+	}
+	return v.Validate(x, []string{})
+}
+
+// refuses documents longer than n bytes before decoding them
+func ValidateJSONLimit(data []byte, v Validator, n int) *Error {
+	if len(data) > n {
+		return &Error{"payload_too_large", []string{}, n}
+	}
+	var x interface{}
+	if err := json.Unmarshal(data, &x); err != nil {
+		return &Error{"invalid_json", []string{}, err.Error()}
+	}
+	return v.Validate(x, []string{})
+}
+
+// decodes a top-level JSON array from r one element at a time, validating
+// each with e and passing the result to c, so the array is never held in
+// memory as a whole. the returned error is about the stream itself: invalid
+// JSON or a value that isn't an array
+func ValidateArrayStream(r io.Reader, e Validator, c func(int, *Error)) *Error {
+	d := json.NewDecoder(r)
+	t, err := d.Token()
+	if err != nil {
+		return &Error{"invalid_json", []string{}, err.Error()}
+	}
+	if t != json.Delim('[') {
+		return &Error{"value_must_be_array", []string{}, nil}
+	}
+	for i := 0; d.More(); i++ {
+		var x interface{}
+		if err := d.Decode(&x); err != nil {
+			return &Error{"invalid_json", []string{strconv.Itoa(i)}, err.Error()}
+		}
+		c(i, e.Validate(x, []string{strconv.Itoa(i)}))
+	}
+	if _, err := d.Token(); err != nil {
+		return &Error{"invalid_json", []string{}, err.Error()}
+	}
+	return NoError
+}
+
+// like ValidateStream for a complete document, but rejects objects that
+// repeat a key, which decoding into a map would silently collapse
+func ValidateStrict(data []byte, v Validator) *Error {
+	d := json.NewDecoder(bytes.NewReader(data))
+	x, e := decodeStrict(d, []string{})
+	if e != nil {
+		return e
+	}
+	if _, err := d.Token(); err != io.EOF {
+		return &Error{"invalid_json", []string{}, "trailing data after JSON value"}
+	}
+	return v.Validate(x, []string{})
+}
+
+func decodeStrict(d *json.Decoder, p []string) (interface{}, *Error) {
+	t, err := d.Token()
+	if err != nil {
+		return nil, &Error{"invalid_json", p, err.Error()}
+	}
+	switch t {
+	case json.Delim('{'):
+		o := make(map[string]interface{})
+		for d.More() {
+			t, err := d.Token()
+			if err != nil {
+				return nil, &Error{"invalid_json", p, err.Error()}
+			}
+			k := t.(string)
+			if _, x := o[k]; x {
+				return nil, &Error{"duplicate_object_key", p, k}
+			}
+			u, e := decodeStrict(d, append(p[:len(p):len(p)], k))
+			if e != nil {
+				return nil, e
+			}
+			o[k] = u
+		}
+		if _, err := d.Token(); err != nil {
+			return nil, &Error{"invalid_json", p, err.Error()}
+		}
+		return o, NoError
+	case json.Delim('['):
+		a := make([]interface{}, 0, 8)
+		for i := 0; d.More(); i++ {
+			u, e := decodeStrict(d, append(p[:len(p):len(p)], strconv.Itoa(i)))
+			if e != nil {
+				return nil, e
+			}
+			a = append(a, u)
+		}
+		if _, err := d.Token(); err != nil {
+			return nil, &Error{"invalid_json", p, err.Error()}
+		}
+		return a, NoError
+	}
+	return t, NoError
+}
+
+// checks that every object in data lists its keys in strictly increasing
+// byte order, as canonical JSON requires, which decoding into a map can't
+// tell. the error is at the object and carries the first key out of order
+func CanonicalKeyOrder(data []byte) *Error {
+	d := json.NewDecoder(bytes.NewReader(data))
+	if e := checkCanonical(d, []string{}); e != nil {
+		return e
+	}
+	if _, err := d.Token(); err != io.EOF {
+		return &Error{"invalid_json", []string{}, "trailing data after JSON value"}
+	}
+	return NoError
+}
+
+func checkCanonical(d *json.Decoder, p []string) *Error {
+	t, err := d.Token()
+	if err != nil {
+		return &Error{"invalid_json", p, err.Error()}
+	}
+	switch t {
+	case json.Delim('{'):
+		for i, l := 0, ""; d.More(); i++ {
+			t, err := d.Token()
+			if err != nil {
+				return &Error{"invalid_json", p, err.Error()}
+			}
+			k := t.(string)
+			if i > 0 && k <= l {
+				return &Error{"object_keys_not_canonical", p, k}
+			}
+			l = k
+			if e := checkCanonical(d, append(p[:len(p):len(p)], k)); e != nil {
+				return e
+			}
+		}
+	case json.Delim('['):
+		for i := 0; d.More(); i++ {
+			if e := checkCanonical(d, append(p[:len(p):len(p)], strconv.Itoa(i))); e != nil {
+				return e
+			}
+		}
+	default:
+		return NoError
+	}
+	if _, err := d.Token(); err != nil {
+		return &Error{"invalid_json", p, err.Error()}
+	}
+	return NoError
+}
@@ -0,0 +1,228 @@
+package jval
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+)
+
+// writes a JSON array of n objects to a pipe as it's being read
+func streamArray(n int, bad func(int) bool) io.Reader {
+	r, w := io.Pipe()
+	go func() {
+		io.WriteString(w, "[")
+		for i := 0; i < n; i++ {
+			if i > 0 {
+				io.WriteString(w, ",\n")
+			}
+			if bad(i) {
+				fmt.Fprintf(w, `{"id":"%d"}`, i)
+			} else {
+				fmt.Fprintf(w, `{"id":%d}`, i)
+			}
+		}
+		io.WriteString(w, "]")
+		w.Close()
+	}()
+	return r
+}
+
+func TestValidateStream(t *testing.T) {
+	v := Object(map[string]Validator{"id": Number()})
+	cs := []struct {
+		name  string
+		data  string
+		label string
+	}{
+		{"valid", `{"id":1}`, ""},
+		{"invalid", `{"id":"1"}`, "value_must_be_number"},
+		{"malformed", `{"id":`, "invalid_json"},
+	}
+	for _, c := range cs {
+		t.Run(c.name, func(t *testing.T) {
+			e := ValidateStream(strings.NewReader(c.data), v)
+			if c.label == "" && e != nil || c.label != "" && !hasLabel(e, c.label) {
+				t.Fatalf("got %v, want %q", e, c.label)
+			}
+		})
+	}
+}
+
+func TestValidateArrayStream(t *testing.T) {
+	n, failed := 100000, []int{}
+	e := ValidateArrayStream(streamArray(n, func(i int) bool { return i%25000 == 7 }), Object(map[string]Validator{"id": Number()}), func(i int, e *Error) {
+		if e != nil {
+			if l := e.Leaves()[0]; strings.Join(l.Field, ".") != fmt.Sprintf("%d.id", i) {
+				t.Errorf("field %v", l.Field)
+			}
+			failed = append(failed, i)
+		}
+	})
+	if e != nil {
+		t.Fatal(e)
+	}
+	if fmt.Sprint(failed) != "[7 25007 50007 75007]" {
+		t.Fatalf("failed %v", failed)
+	}
+	cs := []struct {
+		name  string
+		data  string
+		label string
+	}{
+		{"not an array", `{}`, "value_must_be_array"},
+		{"truncated", `[1,`, "invalid_json"},
+		{"empty", `[]`, ""},
+	}
+	for _, c := range cs {
+		t.Run(c.name, func(t *testing.T) {
+			e := ValidateArrayStream(strings.NewReader(c.data), Number(), func(int, *Error) {})
+			if c.label == "" && e != nil || c.label != "" && (e == nil || e.Label != c.label) {
+				t.Fatalf("got %v, want %q", e, c.label)
+			}
+		})
+	}
+}
+
+func TestValidateStrict(t *testing.T) {
+	v := Object(map[string]Validator{"a": Anything(), "b": Optional(Anything())})
+	cs := []struct {
+		name  string
+		data  string
+		label string
+		field string
+	}{
+		{"valid", `{"a":1,"b":[{"x":1,"y":2}]}`, "", ""},
+		{"top level duplicate", `{"a":1,"a":2}`, "duplicate_object_key", ""},
+		{"nested duplicate", `{"a":{"x":{"y":1,"y":2}}}`, "duplicate_object_key", "a.x"},
+		{"duplicate in array", `{"a":1,"b":[{},{"x":1,"x":1}]}`, "duplicate_object_key", "b.1"},
+		{"same key in siblings", `{"a":{"x":1},"b":{"x":1}}`, "", ""},
+		{"trailing data", `{"a":1} {}`, "invalid_json", ""},
+		{"malformed", `{"a":`, "invalid_json", "a"},
+		{"validated after decoding", `{"b":1}`, "missing_object_key", ""},
+	}
+	for _, c := range cs {
+		t.Run(c.name, func(t *testing.T) {
+			e := ValidateStrict([]byte(c.data), v)
+			if c.label == "" {
+				if e != nil {
+					t.Fatalf("unexpected %v", e)
+				}
+				return
+			}
+			if !hasLabel(e, c.label) {
+				t.Fatalf("got %v, want %s", e, c.label)
+			}
+			if e.Label == c.label && strings.Join(e.Field, ".") != c.field {
+				t.Fatalf("field %v, want %s", e.Field, c.field)
+			}
+		})
+	}
+	if e := ValidateStrict([]byte(`{"a":{"k":1,"k":2}}`), v); e.Context != "k" {
+		t.Fatalf("context %v", e.Context)
+	}
+}
+
+func BenchmarkValidateStrict(b *testing.B) {
+	data := []byte(`{"a":{"x":1,"y":[1,2,3]},"b":[{"x":1,"y":2},{"x":3}]}`)
+	v := Object(map[string]Validator{"a": Anything(), "b": Anything()})
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		ValidateStrict(data, v)
+	}
+}
+
+func TestValidateLimit(t *testing.T) {
+	v := Array(Number())
+	under := `[1,2,3]`
+	cs := []struct {
+		name  string
+		data  string
+		n     int
+		label string
+	}{
+		{"just under", under, len(under) + 1, ""},
+		{"at the limit", under, len(under), ""},
+		{"oversized", under, len(under) - 1, "payload_too_large"},
+		{"invalid within limit", `[1,`, 10, "invalid_json"},
+		{"validated", `["a"]`, 10, "value_must_be_number"},
+	}
+	for _, c := range cs {
+		t.Run(c.name, func(t *testing.T) {
+			for _, e := range []*Error{
+				ValidateJSONLimit([]byte(c.data), v, c.n),
+				ValidateStreamLimit(strings.NewReader(c.data), v, int64(c.n)),
+			} {
+				if c.label == "" && e != nil || c.label != "" && !hasLabel(e, c.label) {
+					t.Fatalf("got %v, want %q", e, c.label)
+				}
+				if c.label == "payload_too_large" && fmt.Sprint(e.Context) != fmt.Sprint(c.n) {
+					t.Fatalf("context %v", e.Context)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkValidateStreamLimit(b *testing.B) {
+	data := `[1,2,3,4,5,6,7,8,9,10]`
+	v := Array(Number())
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		ValidateStreamLimit(strings.NewReader(data), v, 1024)
+	}
+}
+
+func BenchmarkValidateArrayStream(b *testing.B) {
+	v := Object(map[string]Validator{"id": Number()})
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		ValidateArrayStream(streamArray(1000, func(int) bool { return false }), v, func(int, *Error) {})
+	}
+}
+
+func TestCanonicalKeyOrder(t *testing.T) {
+	cs := []struct {
+		name  string
+		data  string
+		label string
+		field string
+		key   interface{}
+	}{
+		{"sorted", `{"a":1,"b":{"c":2,"d":[{"e":1,"f":2}]}}`, "", "", nil},
+		{"scalar", `1`, "", "", nil},
+		{"empty object", `{}`, "", "", nil},
+		{"top level", `{"b":1,"a":2}`, "object_keys_not_canonical", "", "a"},
+		{"nested", `{"a":{"x":1,"c":2},"b":1}`, "object_keys_not_canonical", "a", "c"},
+		{"inside array", `{"a":[{"a":1},{"b":1,"a":2}]}`, "object_keys_not_canonical", "a.1", "a"},
+		{"duplicate", `{"a":1,"a":2}`, "object_keys_not_canonical", "", "a"},
+		{"byte order", `{"a":1,"B":2}`, "object_keys_not_canonical", "", "B"},
+		{"invalid", `{"a":}`, "invalid_json", "a", nil},
+		{"trailing", `{} {}`, "invalid_json", "", nil},
+	}
+	for _, c := range cs {
+		t.Run(c.name, func(t *testing.T) {
+			e := CanonicalKeyOrder([]byte(c.data))
+			if c.label == "" {
+				if e != nil {
+					t.Fatalf("unexpected error %v", e)
+				}
+				return
+			}
+			if e == nil || e.Label != c.label || strings.Join(e.Field, ".") != c.field {
+				t.Fatalf("got %v, want %s at %q", e, c.label, c.field)
+			}
+			if c.key != nil && e.Context != c.key {
+				t.Fatalf("context %v, want %v", e.Context, c.key)
+			}
+		})
+	}
+}
+
+func BenchmarkCanonicalKeyOrder(b *testing.B) {
+	data := []byte(`{"a":1,"b":{"c":2,"d":[{"e":1,"f":2},{"e":3,"f":4}]},"g":"x"}`)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		CanonicalKeyOrder(data)
+	}
+}